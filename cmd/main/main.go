@@ -1,34 +1,69 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"github.com/crewblade/notes_service/internal/app"
 	"github.com/crewblade/notes_service/internal/config"
+	"github.com/crewblade/notes_service/internal/lib/clock"
+	"github.com/crewblade/notes_service/internal/lib/idgen"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"github.com/crewblade/notes_service/internal/lib/tracing"
+	"github.com/crewblade/notes_service/internal/migrator"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
-	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	cfg := config.MustLoad()
+	log, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		panic("cannot set up logger: " + err.Error())
+	}
+	flag.StringVar(&cfg.MigrationsPath, "migrations-path", cfg.MigrationsPath, "read migrations from this directory instead of the embedded ones")
+	flag.Parse()
 	log.Info("starting application",
 		slog.Any("cfg", cfg))
-	application := app.New(
-		log,
-		cfg.GRPC.Port,
-		cfg.ConnectionString,
-	)
-	go application.GRPCSrv.MustRun()
-
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to set up tracing", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+	if cfg.AutoMigrate {
+		if err := migrator.Up(log, cfg.ConnectionString, cfg.MigrationsPath); err != nil {
+			log.Error("refusing to start", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+	}
+	application := app.New(log, cfg, idgen.UUIDv7{}, clock.System{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
-	signal := <-stop
-	log.Info("application stopped with signal:" + signal.String())
+	go func() {
+		signal := <-stop
+		log.Info("stopping application on signal: " + signal.String())
+		cancel()
+	}()
 
-	if err := application.Storage.Close(); err != nil {
-		log.Error("failed to close database connection", slog.String("err", err.Error()))
+	err = application.Run(ctx)
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Error("failed to flush traces", slog.String("err", err.Error()))
+	}
+	if err != nil {
+		log.Error("application failed", slog.String("err", err.Error()))
+		os.Exit(1)
 	}
-	application.GRPCSrv.Stop()
-
 }
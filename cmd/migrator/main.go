@@ -1,47 +1,192 @@
 package main
 
 import (
-	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/crewblade/notes_service/internal/migrator"
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
 )
 
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	// exitLocked means another migrator holds the lock.
+	exitLocked
+)
+
+const usage = `usage: migrator -storage-path DSN [flags] COMMAND [ARGS]
+
+commands:
+  up                apply all pending migrations (default)
+  down N            roll back the last N migrations
+  goto VERSION      migrate up or down to VERSION
+  status            print the current version, dirty flag and pending migrations
+  force VERSION     set VERSION and clear the dirty flag without running migrations
+  create NAME       write empty timestamped up/down files to the migrations path
+
+Migrations compiled into the binary are used unless -migrations-path is set.
+
+flags:
+`
+
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("migrator", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
 	var storagePath, migrationsPath string
-	flag.StringVar(&storagePath, "storage-path", "", "path to storage")
-	flag.StringVar(&migrationsPath, "migrations-path", "", "path to migrations")
-	flag.Parse()
-	if storagePath == "" {
-		panic("storagePath is required")
+	var lockTimeout time.Duration
+	flags.StringVar(&storagePath, "storage-path", "", "path to storage")
+	flags.StringVar(&migrationsPath, "migrations-path", "", "read migrations from this directory instead of the embedded ones")
+	flags.DurationVar(&lockTimeout, "lock-timeout", 15*time.Second, "how long to wait for another migrator to finish")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
 	}
-	if migrationsPath == "" {
-		panic("migrationsPath is required")
+
+	command, cmdArgs := "up", flags.Args()
+	if len(cmdArgs) > 0 {
+		command, cmdArgs = cmdArgs[0], cmdArgs[1:]
 	}
-	db, err := sql.Open("postgres", storagePath)
-	if err != nil {
-		panic(err)
+	if command == "create" {
+		if migrationsPath == "" {
+			fmt.Fprintln(stderr, "create: -migrations-path is required")
+			return exitUsage
+		}
+		if len(cmdArgs) != 1 {
+			flags.Usage()
+			return exitUsage
+		}
+		return exitCode(stderr, create(stdout, migrationsPath, cmdArgs[0], time.Now()))
 	}
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	var cmd func(m *migrate.Migrate) error
+	switch command {
+	case "up":
+		cmd = func(m *migrate.Migrate) error { return m.Up() }
+	case "down", "goto", "force":
+		if len(cmdArgs) != 1 {
+			flags.Usage()
+			return exitUsage
+		}
+		n, err := strconv.ParseUint(cmdArgs[0], 10, 64)
+		if err != nil || (command == "down" && n == 0) {
+			fmt.Fprintf(stderr, "%s: %q is not a valid number\n", command, cmdArgs[0])
+			return exitUsage
+		}
+		switch command {
+		case "down":
+			cmd = func(m *migrate.Migrate) error { return m.Steps(-int(n)) }
+		case "goto":
+			cmd = func(m *migrate.Migrate) error { return m.Migrate(uint(n)) }
+		case "force":
+			cmd = func(m *migrate.Migrate) error { return m.Force(int(n)) }
+		}
+	case "status":
+		cmd = func(m *migrate.Migrate) error { return status(stdout, m, migrationsPath) }
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", command)
+		flags.Usage()
+		return exitUsage
+	}
+	if storagePath == "" {
+		fmt.Fprintln(stderr, "-storage-path is required")
+		return exitUsage
+	}
+
+	m, err := migrator.New(storagePath, migrationsPath, lockTimeout)
 	if err != nil {
-		panic(err)
+		return exitCode(stderr, err)
+	}
+	defer m.Close()
+	err = cmd(m)
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Fprintln(stdout, "no migrations to apply")
+		return exitOK
+	}
+	return exitCode(stderr, err)
+}
+
+func exitCode(stderr io.Writer, err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, migrate.ErrLockTimeout), errors.Is(err, migrate.ErrLocked):
+		fmt.Fprintln(stderr, "another migrator is running:", err)
+		return exitLocked
+	default:
+		fmt.Fprintln(stderr, "error:", err)
+		return exitError
+	}
+}
+
+func status(stdout io.Writer, m *migrate.Migrate, migrationsPath string) error {
+	version, dirty, err := m.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		fmt.Fprintln(stdout, "version: none")
+	case err != nil:
+		return err
+	default:
+		fmt.Fprintf(stdout, "version: %d\n", version)
 	}
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationsPath,
-		"postgres", driver)
+	fmt.Fprintf(stdout, "dirty: %t\n", dirty)
+
+	versions, err := migrator.Versions(migrationsPath)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	if err := m.Up(); err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			fmt.Println("no migrations to apply")
-			return
+	var pending []uint
+	for _, v := range versions {
+		if v > version {
+			pending = append(pending, v)
 		}
-		panic(err)
 	}
+	fmt.Fprintf(stdout, "pending: %d\n", len(pending))
+	for _, v := range pending {
+		fmt.Fprintf(stdout, "  %d\n", v)
+	}
+	return nil
+}
 
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// create scaffolds a migration pair versioned by UTC timestamp, which keeps
+// versions increasing across branches without coordination.
+func create(stdout io.Writer, migrationsPath, name string, now time.Time) error {
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("migration name %q must match %s", name, migrationName)
+	}
+	version := now.UTC().Format("20060102150405")
+	var created []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(migrationsPath, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			created = append(created, path)
+			err = f.Close()
+		}
+		if err != nil {
+			for _, p := range created {
+				os.Remove(p)
+			}
+			return err
+		}
+	}
+	for _, path := range created {
+		fmt.Fprintln(stdout, "created", path)
+	}
+	return nil
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/importer"
+	pb "github.com/crewblade/notes_service/protos/gen/go/notes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var addr, format string
+	var dryRun bool
+	var timeout time.Duration
+	fs.StringVar(&addr, "addr", "localhost:8088", "notes service address")
+	fs.StringVar(&format, "format", string(importer.FormatAuto), "input format: auto, markdown, jsonl or enex")
+	fs.BoolVar(&dryRun, "dry-run", false, "report what would be imported without writing anything")
+	fs.DurationVar(&timeout, "timeout", 5*time.Minute, "import timeout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: notesctl import [flags] <dir|archive|file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	parsed, err := importer.Read(fs.Arg(0), importer.Format(format))
+	if err != nil {
+		return err
+	}
+	if len(parsed.Notes) == 0 && len(parsed.Failed) == 0 {
+		return errors.New("no notes found")
+	}
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := importNotes(ctx, pb.NewNotesClient(conn), parsed.Notes, dryRun)
+	if err != nil {
+		return err
+	}
+	return printImportReport(resp, parsed)
+}
+
+func importNotes(ctx context.Context, client pb.NotesClient, notes []models.ImportNote, dryRun bool) (*pb.ImportNotesResponse, error) {
+	stream, err := client.ImportNotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		req := &pb.ImportNotesRequest{
+			DryRun: dryRun,
+			Note: &pb.ImportNote{
+				Source:        note.Source,
+				Id:            note.Id,
+				Title:         note.Title,
+				Content:       note.Content,
+				ContentFormat: contentFormatToProto(note.Format),
+				Tags:          note.Tags,
+			},
+		}
+		if !note.CreatedAt.IsZero() {
+			req.Note.CreatedAt = timestamppb.New(note.CreatedAt)
+		}
+		if !note.UpdatedAt.IsZero() {
+			req.Note.UpdatedAt = timestamppb.New(note.UpdatedAt)
+		}
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+func printImportReport(resp *pb.ImportNotesResponse, parsed *importer.Result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tSTATUS\tID\tMESSAGE")
+	for _, f := range parsed.Failed {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Source, f.Status, f.Id, f.Message)
+	}
+	for _, r := range resp.GetResults() {
+		msg := r.GetMessage()
+		if warnings := parsed.Warnings[r.GetSource()]; len(warnings) > 0 {
+			if msg != "" {
+				msg += "; "
+			}
+			msg += "unsupported: " + strings.Join(warnings, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.GetSource(), importStatusName(r.GetStatus()), r.GetId(), msg)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	failed := resp.GetFailed() + int32(len(parsed.Failed))
+	prefix := ""
+	if resp.GetDryRun() {
+		prefix = "dry run: "
+	}
+	fmt.Printf("\n%simported %d, duplicates %d, failed %d\n", prefix, resp.GetImported(), resp.GetDuplicates(), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d notes failed to import", failed)
+	}
+	return nil
+}
+
+func importStatusName(s pb.ImportStatus) string {
+	switch s {
+	case pb.ImportStatus_IMPORT_STATUS_IMPORTED:
+		return models.ImportStatusImported.String()
+	case pb.ImportStatus_IMPORT_STATUS_DUPLICATE:
+		return models.ImportStatusDuplicate.String()
+	case pb.ImportStatus_IMPORT_STATUS_FAILED:
+		return models.ImportStatusFailed.String()
+	default:
+		return "unknown"
+	}
+}
+
+func contentFormatToProto(f models.ContentFormat) pb.ContentFormat {
+	switch f {
+	case models.ContentFormatPlain:
+		return pb.ContentFormat_CONTENT_FORMAT_PLAIN
+	case models.ContentFormatMarkdown:
+		return pb.ContentFormat_CONTENT_FORMAT_MARKDOWN
+	default:
+		return pb.ContentFormat_CONTENT_FORMAT_UNSPECIFIED
+	}
+}
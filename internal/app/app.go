@@ -1,27 +1,127 @@
 package app
 
 import (
+	gcapp "github.com/crewblade/notes_service/internal/app/gc"
 	grpcapp "github.com/crewblade/notes_service/internal/app/grpc"
+	healthapp "github.com/crewblade/notes_service/internal/app/health"
+	metricsapp "github.com/crewblade/notes_service/internal/app/metrics"
+	"github.com/crewblade/notes_service/internal/config"
+	"github.com/crewblade/notes_service/internal/grpc/interceptors"
+	"github.com/crewblade/notes_service/internal/lib/ratelimit"
+	"github.com/crewblade/notes_service/internal/lib/validate"
+	"github.com/crewblade/notes_service/internal/markdown"
+	"github.com/crewblade/notes_service/internal/metrics"
+	"github.com/crewblade/notes_service/internal/services/attachments"
 	"github.com/crewblade/notes_service/internal/services/notes"
+	"github.com/crewblade/notes_service/internal/storage/localfs"
 	"github.com/crewblade/notes_service/internal/storage/postgres"
 	"log/slog"
+	"net/netip"
 )
 
 type App struct {
 	GRPCSrv *grpcapp.App
+	GC      *gcapp.App
+	Health  *healthapp.App
+	// Metrics is nil when the metrics listener is disabled.
+	Metrics *metricsapp.App
 	Storage *postgres.Storage
+
+	log      *slog.Logger
+	shutdown config.ShutdownConfig
 }
 
-func New(log *slog.Logger, grpcPort int, connectionString string) *App {
-	storage, err := postgres.New(connectionString)
+func New(log *slog.Logger, cfg *config.Config, ids notes.IDGenerator, clock postgres.Clock) *App {
+	m := metrics.New()
+	storage, err := postgres.New(log, cfg.ConnectionString, postgres.Options{
+		MaxOpenConns:     cfg.Database.MaxOpenConns,
+		MaxIdleConns:     cfg.Database.MaxIdleConns,
+		ConnMaxLifetime:  cfg.Database.ConnMaxLifetime,
+		StatementTimeout: cfg.Database.StatementTimeout,
+
+		Replicas:             cfg.Database.Replicas,
+		ReplicaMaxLag:        cfg.Database.ReplicaMaxLag,
+		ReplicaCheckInterval: cfg.Database.ReplicaCheckInterval,
+		ReadYourWritesWindow: cfg.Database.ReadYourWritesWindow,
+
+		RetryMaxAttempts: cfg.Database.RetryMaxAttempts,
+		RetryBaseDelay:   cfg.Database.RetryBaseDelay,
+		RetryMaxDelay:    cfg.Database.RetryMaxDelay,
+
+		Observer: m,
+	}, clock)
+	if err != nil {
+		panic(err)
+	}
+	blobStorage, err := localfs.New(cfg.Attachments.Path)
 	if err != nil {
 		panic(err)
 	}
 
-	notesService := notes.New(log, storage, storage, storage, storage, storage)
-	grpcApp := grpcapp.New(log, notesService, grpcPort)
+	renderer := markdown.NewRenderer(cfg.RenderCacheSize)
+	notesService := notes.New(log, storage, storage, storage, storage, storage, storage, renderer, ids, clock, validate.Limits{
+		MaxTitleLength:  cfg.Limits.MaxTitleLength,
+		MaxContentBytes: cfg.Limits.MaxContentBytes,
+		MaxPageSize:     cfg.Limits.MaxPageSize,
+		MaxImportNotes:  cfg.Limits.MaxImportNotes,
+	}, m)
+	attachmentsService := attachments.New(
+		log, storage, storage, storage, storage, blobStorage,
+		cfg.Attachments.MaxSize, cfg.Attachments.AllowedTypes, ids, clock,
+	)
+	var rateLimitStore interceptors.RateLimitStore
+	switch cfg.RateLimit.Store {
+	case "memory":
+		rateLimitStore = ratelimit.NewMemory(clock)
+	case "postgres":
+		rateLimitStore = storage
+	default:
+		panic("unknown rate limit store: " + cfg.RateLimit.Store)
+	}
+	methodLimits := make(map[string]ratelimit.Limit, len(cfg.RateLimit.Methods))
+	for method, l := range cfg.RateLimit.Methods {
+		methodLimits[method] = ratelimit.Limit{Rate: l.Rate, Burst: l.Burst}
+	}
+	trustedProxies := make([]netip.Prefix, 0, len(cfg.GRPC.TrustedProxies))
+	for _, proxy := range cfg.GRPC.TrustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				panic("invalid trusted proxy: " + proxy)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trustedProxies = append(trustedProxies, prefix)
+	}
+	grpcApp := grpcapp.New(log, notesService, attachmentsService, storage, rateLimitStore, grpcapp.Options{
+		Port:           cfg.GRPC.Port,
+		Timeout:        cfg.GRPC.Timeout,
+		MethodTimeouts: cfg.GRPC.MethodTimeouts,
+		Repanic:        cfg.GRPC.Repanic,
+		TrustedProxies: trustedProxies,
+
+		IdempotencyTTL: cfg.IdempotencyTTL,
+		DefaultLimit:   ratelimit.Limit{Rate: cfg.RateLimit.Default.Rate, Burst: cfg.RateLimit.Default.Burst},
+		MethodLimits:   methodLimits,
+
+		Registerer: m.Registerer(),
+	})
+	gcApp := gcapp.New(log, attachmentsService, storage, cfg.Attachments.GCInterval, cfg.Attachments.GCGracePeriod)
+	m.RegisterStorage(storage)
+	var metricsApp *metricsapp.App
+	if cfg.Metrics.Port != 0 {
+		metricsApp = metricsapp.New(log, m.Handler(), cfg.Metrics.Port, cfg.Metrics.Path)
+	}
+	healthApp := healthapp.New(log, storage, grpcApp, cfg.Health.CheckInterval, cfg.Health.CheckTimeout)
 	return &App{
 		GRPCSrv: grpcApp,
+		GC:      gcApp,
+		Health:  healthApp,
+		Metrics: metricsApp,
 		Storage: storage,
+
+		log:      log,
+		shutdown: cfg.Shutdown,
 	}
 }
@@ -0,0 +1,90 @@
+package gcapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Collector interface {
+	CollectGarbage(ctx context.Context, gracePeriod time.Duration) (removed int, err error)
+}
+
+type Expirer interface {
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+	DeleteIdleRateLimits(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+// rateLimitIdle is how long a rate limit bucket stays unused before it is
+// dropped. Buckets of limits that take longer to refill restart full.
+const rateLimitIdle = time.Hour
+
+// App periodically removes attachment blobs that are no longer referenced,
+// expired idempotency keys and idle rate limit buckets.
+type App struct {
+	log         *slog.Logger
+	collector   Collector
+	expirer     Expirer
+	interval    time.Duration
+	gracePeriod time.Duration
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func New(log *slog.Logger, collector Collector, expirer Expirer, interval, gracePeriod time.Duration) *App {
+	return &App{
+		log:         log,
+		collector:   collector,
+		expirer:     expirer,
+		interval:    interval,
+		gracePeriod: gracePeriod,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Run blocks until Stop is called.
+func (a *App) Run() {
+	const op = "gcapp.Run"
+	log := a.log.With(slog.String("op", op))
+	defer close(a.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-a.stop
+		cancel()
+	}()
+
+	log.Info("Starting garbage collection", slog.Duration("interval", a.interval))
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.collector.CollectGarbage(ctx, a.gracePeriod); err != nil && ctx.Err() == nil {
+				log.Error("garbage collection failed", slog.String("err", err.Error()))
+			}
+			if n, err := a.expirer.DeleteExpiredIdempotencyKeys(ctx); err != nil && ctx.Err() == nil {
+				log.Error("failed to delete expired idempotency keys", slog.String("err", err.Error()))
+			} else if n > 0 {
+				log.Info("Expired idempotency keys deleted", slog.Int64("count", n))
+			}
+			if n, err := a.expirer.DeleteIdleRateLimits(ctx, rateLimitIdle); err != nil && ctx.Err() == nil {
+				log.Error("failed to delete idle rate limits", slog.String("err", err.Error()))
+			} else if n > 0 {
+				log.Debug("Idle rate limits deleted", slog.Int64("count", n))
+			}
+		}
+	}
+}
+
+// Stop interrupts a running collection and waits for Run to return.
+func (a *App) Stop() {
+	const op = "gcapp.Stop"
+	a.log.With(slog.String("op", op)).Info("Stopping garbage collection")
+	close(a.stop)
+	<-a.done
+}
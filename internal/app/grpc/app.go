@@ -2,28 +2,84 @@ package grpcapp
 
 import (
 	"fmt"
+	"github.com/crewblade/notes_service/internal/grpc/interceptors"
 	notesrpc "github.com/crewblade/notes_service/internal/grpc/notes"
+	"github.com/crewblade/notes_service/internal/lib/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"log/slog"
 	"net"
+	"net/netip"
+	"sync"
+	"time"
 )
 
 type App struct {
 	log        *slog.Logger
 	gRPCServer *grpc.Server
 	port       int
+
+	health       *health.Server
+	healthMu     sync.Mutex
+	shuttingDown bool
+}
+
+// Options configure the server and its interceptors.
+type Options struct {
+	Port int
+	// Timeout applies to calls without a deadline; MethodTimeouts override it per full method name.
+	Timeout        time.Duration
+	MethodTimeouts map[string]time.Duration
+	// Repanic crashes the process after a recovered panic is logged, for development.
+	Repanic bool
+	// TrustedProxies may name the client they forward a call for.
+	TrustedProxies []netip.Prefix
+
+	IdempotencyTTL time.Duration
+	DefaultLimit   ratelimit.Limit
+	MethodLimits   map[string]ratelimit.Limit
+
+	// Registerer receives the RPC and panic metrics.
+	Registerer prometheus.Registerer
 }
 
-func New(log *slog.Logger, notesService notesrpc.Notes, port int) *App {
-	gRPCServer := grpc.NewServer()
+func New(
+	log *slog.Logger,
+	notesService notesrpc.Notes,
+	attachmentsService notesrpc.Attachments,
+	idempotencyStore interceptors.IdempotencyStore,
+	rateLimitStore interceptors.RateLimitStore,
+	opts Options,
+) *App {
+	caller := interceptors.NewCaller(opts.TrustedProxies)
+	deadline := interceptors.NewDeadline(opts.Timeout, opts.MethodTimeouts)
+	logging := interceptors.NewLogging(log)
+	metrics := interceptors.NewMetrics(opts.Registerer)
+	recovery := interceptors.NewRecovery(log, opts.Registerer, opts.Repanic)
+	rateLimit := interceptors.NewRateLimit(log, rateLimitStore, opts.DefaultLimit, opts.MethodLimits)
+	idempotency := interceptors.NewIdempotency(log, idempotencyStore, opts.IdempotencyTTL, notesrpc.MutatingMethods)
+	gRPCServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(caller.Unary(), logging.Unary(), metrics.Unary(), recovery.Unary(), deadline.Unary(), rateLimit.Unary(), idempotency.Unary()),
+		grpc.ChainStreamInterceptor(caller.Stream(), logging.Stream(), metrics.Stream(), recovery.Stream(), deadline.Stream(), rateLimit.Stream(), idempotency.Stream()),
+	)
 	reflection.Register(gRPCServer)
-	notesrpc.Register(gRPCServer, notesService)
-	return &App{
+	notesrpc.Register(gRPCServer, notesService, attachmentsService)
+	a := &App{
 		log:        log,
 		gRPCServer: gRPCServer,
-		port:       port,
+		port:       opts.Port,
+		health:     health.NewServer(),
 	}
+	healthpb.RegisterHealthServer(gRPCServer, a.health)
+	a.health.SetServingStatus(LivenessService, healthpb.HealthCheckResponse_SERVING)
+	// Not ready until the first database check passes.
+	a.SetServing(false)
+	return a
 }
 func (a *App) Run() error {
 	const op = "grpcapp.Run"
@@ -41,15 +97,26 @@ func (a *App) Run() error {
 	}
 	return nil
 }
-func (a *App) MustRun() {
-	if err := a.Run(); err != nil {
-		panic(err)
-	}
-}
 
-func (a *App) Stop() {
+// Stop drains in-flight RPCs and, once drainTimeout passes, closes whatever
+// is left, so a stuck stream cannot hold up the shutdown.
+func (a *App) Stop(drainTimeout time.Duration) {
 	const op = "grpcapp.Stop"
 	log := a.log.With(slog.String("op", op))
-	log.Info("Stopping gRPC server", slog.Int("port", a.port))
-	a.gRPCServer.GracefulStop()
+	log.Info("Stopping gRPC server", slog.Int("port", a.port), slog.Duration("drain_timeout", drainTimeout))
+
+	drained := make(chan struct{})
+	go func() {
+		a.gRPCServer.GracefulStop()
+		close(drained)
+	}()
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-timer.C:
+		log.Warn("drain timed out, closing remaining connections")
+		a.gRPCServer.Stop()
+		<-drained
+	}
 }
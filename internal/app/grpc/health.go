@@ -0,0 +1,45 @@
+package grpcapp
+
+import (
+	notesrpc "github.com/crewblade/notes_service/internal/grpc/notes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Health check service names. Liveness reports whether the process should be
+// restarted and stays SERVING until it exits; readiness reports whether it
+// should receive traffic. The empty name and the notes service follow
+// readiness.
+const (
+	LivenessService  = "liveness"
+	ReadinessService = "readiness"
+)
+
+func (a *App) readinessServices() []string {
+	return []string{"", ReadinessService, notesrpc.ServiceName}
+}
+
+// SetServing reports database health. It has no effect once shutdown began.
+func (a *App) SetServing(serving bool) {
+	a.healthMu.Lock()
+	defer a.healthMu.Unlock()
+	if a.shuttingDown {
+		return
+	}
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	for _, service := range a.readinessServices() {
+		a.health.SetServingStatus(service, status)
+	}
+}
+
+// SetNotServing takes the server out of rotation for good, ahead of a stop.
+func (a *App) SetNotServing() {
+	a.healthMu.Lock()
+	defer a.healthMu.Unlock()
+	a.shuttingDown = true
+	for _, service := range a.readinessServices() {
+		a.health.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
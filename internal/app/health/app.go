@@ -0,0 +1,91 @@
+package healthapp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type Reporter interface {
+	SetServing(serving bool)
+}
+
+// App periodically pings the database and reports the result as readiness.
+type App struct {
+	log      *slog.Logger
+	pinger   Pinger
+	reporter Reporter
+	interval time.Duration
+	timeout  time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func New(log *slog.Logger, pinger Pinger, reporter Reporter, interval, timeout time.Duration) *App {
+	return &App{
+		log:      log,
+		pinger:   pinger,
+		reporter: reporter,
+		interval: interval,
+		timeout:  timeout,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run checks right away and then every interval; it blocks until Stop is called.
+func (a *App) Run() {
+	const op = "healthapp.Run"
+	log := a.log.With(slog.String("op", op))
+	defer close(a.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-a.stop
+		cancel()
+	}()
+
+	log.Info("Starting database health checks", slog.Duration("interval", a.interval))
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	healthy := true
+	for {
+		err := a.check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err != nil && healthy:
+			log.Error("database is unavailable", slog.String("err", err.Error()))
+		case err == nil && !healthy:
+			log.Info("database is available again")
+		}
+		healthy = err == nil
+		a.reporter.SetServing(healthy)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	return a.pinger.Ping(ctx)
+}
+
+// Stop interrupts a running check and waits for Run to return.
+func (a *App) Stop() {
+	const op = "healthapp.Stop"
+	a.log.With(slog.String("op", op)).Info("Stopping database health checks")
+	close(a.stop)
+	<-a.done
+}
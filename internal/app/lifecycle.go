@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Run starts the gRPC and metrics servers and the background workers and
+// blocks until ctx is done or a server fails; either way it shuts everything
+// down before returning. A server failure is returned.
+func (a *App) Run(ctx context.Context) error {
+	const op = "app.Run"
+
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- a.GRPCSrv.Run()
+	}()
+	if a.Metrics != nil {
+		go func() {
+			serveErr <- a.Metrics.Run()
+		}()
+	}
+	go a.GC.Run()
+	go a.Health.Run()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		if err == nil {
+			err = fmt.Errorf("%s: server stopped unexpectedly", op)
+		} else {
+			err = fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	a.stop(err == nil)
+	return err
+}
+
+// stop takes the server out of rotation, drains it, stops the metrics server
+// and the workers, and closes storage last, once nothing can use it any more.
+// The pre-stop delay is skipped when a server is already down.
+func (a *App) stop(serving bool) {
+	const op = "app.stop"
+	log := a.log.With(slog.String("op", op))
+
+	a.GRPCSrv.SetNotServing()
+	if serving && a.shutdown.PreStopDelay > 0 {
+		log.Info("Waiting before draining", slog.Duration("delay", a.shutdown.PreStopDelay))
+		time.Sleep(a.shutdown.PreStopDelay)
+	}
+	a.GRPCSrv.Stop(a.shutdown.DrainTimeout)
+	if a.Metrics != nil {
+		a.Metrics.Stop(a.shutdown.DrainTimeout)
+	}
+	a.Health.Stop()
+	a.GC.Stop()
+	if err := a.Storage.Close(); err != nil {
+		log.Error("failed to close database connection", slog.String("err", err.Error()))
+	}
+	log.Info("Application stopped")
+}
@@ -0,0 +1,57 @@
+package metricsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// App serves Prometheus metrics over HTTP.
+type App struct {
+	log    *slog.Logger
+	server *http.Server
+	port   int
+}
+
+func New(log *slog.Logger, handler http.Handler, port int, path string) *App {
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	return &App{
+		log: log,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		port: port,
+	}
+}
+
+func (a *App) Run() error {
+	const op = "metricsapp.Run"
+	log := a.log.With(slog.String("op", op))
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("metrics server is running", slog.String("addr", l.Addr().String()))
+	if err := a.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Stop lets in-flight scrapes finish for up to timeout.
+func (a *App) Stop(timeout time.Duration) {
+	const op = "metricsapp.Stop"
+	log := a.log.With(slog.String("op", op))
+	log.Info("Stopping metrics server", slog.Int("port", a.port))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := a.server.Shutdown(ctx); err != nil {
+		log.Warn("failed to stop metrics server", slog.String("err", err.Error()))
+	}
+}
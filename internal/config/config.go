@@ -9,12 +9,123 @@ import (
 )
 
 type Config struct {
-	ConnectionString string     `yaml:"connection_string"`
-	GRPC             GRPCConfig `yaml:"grpc"`
+	ConnectionString string            `yaml:"connection_string"`
+	Log              LogConfig         `yaml:"log"`
+	Database         DatabaseConfig    `yaml:"database"`
+	GRPC             GRPCConfig        `yaml:"grpc"`
+	Health           HealthConfig      `yaml:"health"`
+	Shutdown         ShutdownConfig    `yaml:"shutdown"`
+	Metrics          MetricsConfig     `yaml:"metrics"`
+	Tracing          TracingConfig     `yaml:"tracing"`
+	RateLimit        RateLimitConfig   `yaml:"rate_limit"`
+	RenderCacheSize  int               `yaml:"render_cache_size" env-default:"1024"`
+	Attachments      AttachmentsConfig `yaml:"attachments"`
+	IdempotencyTTL   time.Duration     `yaml:"idempotency_ttl" env-default:"24h"`
+	Limits           LimitsConfig      `yaml:"limits"`
+	// AutoMigrate applies pending migrations on startup.
+	AutoMigrate bool `yaml:"auto_migrate" env-default:"false"`
+	// MigrationsPath replaces the embedded migrations with a directory, for development.
+	MigrationsPath string `yaml:"migrations_path"`
 }
+type DatabaseConfig struct {
+	MaxOpenConns     int           `yaml:"max_open_conns" env-default:"20"`
+	MaxIdleConns     int           `yaml:"max_idle_conns" env-default:"10"`
+	ConnMaxLifetime  time.Duration `yaml:"conn_max_lifetime" env-default:"30m"`
+	StatementTimeout time.Duration `yaml:"statement_timeout" env-default:"5s"`
+	// Replicas are read replica connection strings; get and list queries go there.
+	Replicas             []string      `yaml:"replicas"`
+	ReplicaMaxLag        time.Duration `yaml:"replica_max_lag" env-default:"10s"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" env-default:"5s"`
+	ReadYourWritesWindow time.Duration `yaml:"read_your_writes_window" env-default:"5s"`
+	// RetryMaxAttempts bounds attempts on transient errors such as failovers and serialization failures.
+	RetryMaxAttempts int           `yaml:"retry_max_attempts" env-default:"3"`
+	RetryBaseDelay   time.Duration `yaml:"retry_base_delay" env-default:"50ms"`
+	RetryMaxDelay    time.Duration `yaml:"retry_max_delay" env-default:"1s"`
+}
+
+// LimitsConfig bounds request fields; zero disables a limit.
+type LimitsConfig struct {
+	MaxTitleLength  int   `yaml:"max_title_length" env-default:"256"`
+	MaxContentBytes int   `yaml:"max_content_bytes" env-default:"1048576"`
+	MaxPageSize     int32 `yaml:"max_page_size" env-default:"100"`
+	MaxImportNotes  int   `yaml:"max_import_notes" env-default:"10000"`
+}
+
 type GRPCConfig struct {
-	Port    int           `yaml:"port"`
+	Port int `yaml:"port"`
+	// Timeout bounds calls whose client sent no shorter deadline; zero disables it.
 	Timeout time.Duration `yaml:"timeout"`
+	// MethodTimeouts overrides Timeout per full method name, e.g. "/notes.Notes/UploadAttachment".
+	MethodTimeouts map[string]time.Duration `yaml:"method_timeouts"`
+	// Repanic crashes the process on a handler panic after logging it, for development.
+	Repanic bool `yaml:"repanic"`
+	// TrustedProxies are addresses or CIDRs of proxies whose x-client-id
+	// header names the caller. Other peers are identified by their address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn and error.
+	Level string `yaml:"level" env-default:"info"`
+	// Format is text or json.
+	Format string `yaml:"format" env-default:"text"`
+}
+
+// HealthConfig controls the database checks behind the readiness status.
+type HealthConfig struct {
+	CheckInterval time.Duration `yaml:"check_interval" env-default:"5s"`
+	CheckTimeout  time.Duration `yaml:"check_timeout" env-default:"1s"`
+}
+
+type ShutdownConfig struct {
+	// PreStopDelay gives load balancers time to notice NOT_SERVING before draining starts.
+	PreStopDelay time.Duration `yaml:"pre_stop_delay" env-default:"5s"`
+	// DrainTimeout bounds how long in-flight RPCs may take to finish.
+	DrainTimeout time.Duration `yaml:"drain_timeout" env-default:"15s"`
+}
+
+type MetricsConfig struct {
+	// Port of the HTTP listener serving Prometheus metrics; zero disables it.
+	Port int    `yaml:"port"`
+	Path string `yaml:"path" env-default:"/metrics"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, otlp or stdout.
+	Exporter    string `yaml:"exporter" env-default:"none"`
+	ServiceName string `yaml:"service_name" env-default:"notes_service"`
+	// Endpoint is the OTLP gRPC collector address.
+	Endpoint string `yaml:"endpoint" env-default:"localhost:4317"`
+	Insecure bool   `yaml:"insecure"`
+	// File receives the stdout exporter output instead of stdout.
+	File        string  `yaml:"file"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+// RateLimitConfig limits calls per caller: the verified client certificate,
+// the x-client-id header set by a trusted proxy, or the peer address.
+type RateLimitConfig struct {
+	// Store is memory, which limits each replica on its own, or postgres,
+	// which shares buckets between replicas.
+	Store   string    `yaml:"store" env-default:"memory"`
+	Default RateLimit `yaml:"default"`
+	// Methods have their own buckets, keyed by full method name, e.g. "/notes.Notes/GetNotes".
+	Methods map[string]RateLimit `yaml:"methods"`
+}
+
+type RateLimit struct {
+	// Rate is in calls per second; zero disables the limit.
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+type AttachmentsConfig struct {
+	Path    string `yaml:"path" env-default:"./data/attachments"`
+	MaxSize int64  `yaml:"max_size" env-default:"26214400"`
+	// AllowedTypes holds MIME types, or prefixes ending with "/".
+	AllowedTypes  []string      `yaml:"allowed_types" env-default:"image/,application/pdf"`
+	GCInterval    time.Duration `yaml:"gc_interval" env-default:"1h"`
+	GCGracePeriod time.Duration `yaml:"gc_grace_period" env-default:"1h"`
 }
 
 func MustLoad() *Config {
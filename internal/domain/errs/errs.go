@@ -0,0 +1,106 @@
+// Package errs defines the error kinds the service reports to clients.
+// Transport layers translate the kind into their own codes; Reason is a
+// stable identifier clients can branch on.
+package errs
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type Kind int
+
+const (
+	Internal Kind = iota
+	NotFound
+	InvalidArgument
+	Conflict
+	PermissionDenied
+	QuotaExceeded
+	// Unavailable is a transient failure; the call may succeed when retried.
+	Unavailable
+)
+
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+type Error struct {
+	Kind Kind
+	// Reason is an UPPER_SNAKE_CASE identifier, stable across releases.
+	Reason string
+	// Message is safe to show to clients.
+	Message    string
+	Metadata   map[string]string
+	Violations []FieldViolation
+	// RetryAfter hints how long a client should wait before retrying.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func New(kind Kind, reason, message string) *Error {
+	return &Error{Kind: kind, Reason: reason, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Invalid reports a single bad field, e.g. Invalid("title", "is required").
+func Invalid(field, description string) *Error {
+	return InvalidFields(FieldViolation{Field: field, Description: description})
+}
+
+// InvalidFields reports every violation found in a request at once.
+func InvalidFields(violations ...FieldViolation) *Error {
+	messages := make([]string, 0, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.Field+" "+v.Description)
+	}
+	return &Error{
+		Kind:       InvalidArgument,
+		Reason:     "INVALID_ARGUMENT",
+		Message:    strings.Join(messages, "; "),
+		Violations: violations,
+	}
+}
+
+// Transient wraps a failure the client may retry after retryAfter.
+func Transient(err error, retryAfter time.Duration) *Error {
+	return &Error{
+		Kind:       Unavailable,
+		Reason:     "TEMPORARILY_UNAVAILABLE",
+		Message:    "service is temporarily unavailable",
+		RetryAfter: retryAfter,
+		Err:        err,
+	}
+}
+
+// RateLimited tells a caller it ran out of requests and may try again
+// after retryAfter.
+func RateLimited(retryAfter time.Duration) *Error {
+	return &Error{
+		Kind:       QuotaExceeded,
+		Reason:     "RATE_LIMITED",
+		Message:    "rate limit exceeded",
+		RetryAfter: retryAfter,
+	}
+}
+
+// KindOf returns the kind of the first *Error in err's chain, or Internal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	Id        string
+	NoteId    string
+	FileName  string
+	MimeType  string
+	Size      int64
+	Sha256    string
+	CreatedAt time.Time
+}
+
+// Blob is attachment content kept in a blob store under its SHA-256 hash.
+type Blob struct {
+	Hash    string
+	Size    int64
+	ModTime time.Time
+}
@@ -0,0 +1,12 @@
+package models
+
+// IdempotencyRecord is the stored outcome of a request made with an
+// idempotency key. Keys are scoped to the caller that chose them.
+// Response is empty while the request is in progress.
+type IdempotencyRecord struct {
+	CallerId    string
+	Key         string
+	Method      string
+	RequestHash string
+	Response    []byte
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+type ImportStatus int
+
+const (
+	ImportStatusImported ImportStatus = iota + 1
+	ImportStatusDuplicate
+	ImportStatusFailed
+)
+
+func (s ImportStatus) String() string {
+	switch s {
+	case ImportStatusImported:
+		return "imported"
+	case ImportStatusDuplicate:
+		return "duplicate"
+	case ImportStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// ImportNote is a note read from an external export.
+// Id, Format, CreatedAt and UpdatedAt are optional.
+type ImportNote struct {
+	Source    string
+	Id        string
+	Title     string
+	Content   string
+	Format    ContentFormat
+	Tags      []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ImportResult struct {
+	Source  string
+	Id      string
+	Status  ImportStatus
+	Message string
+}
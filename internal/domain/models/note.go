@@ -1,7 +1,52 @@
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+)
+
 type Note struct {
-	Id      string
-	Title   string
-	Content string
+	Id            string
+	Title         string
+	Content       string
+	ContentFormat ContentFormat
+	Tags          []string
+	// SourceUrl is the page a clipped note was created from.
+	SourceUrl string
+}
+
+// LogValue keeps note content out of logs.
+func (n Note) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", n.Id),
+		slog.Int("content_bytes", len(n.Content)),
+		slog.String("content_format", string(n.ContentFormat)),
+	)
+}
+
+// ContentHash returns the hex encoded SHA-256 of the note content.
+// It is stored alongside the note and used to detect duplicates on import.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+type ContentFormat string
+
+const (
+	ContentFormatPlain    ContentFormat = "plain"
+	ContentFormatMarkdown ContentFormat = "markdown"
+)
+
+// RenderedContent is note content rendered to sanitized HTML.
+type RenderedContent struct {
+	HTML    string
+	Outline []Heading
+}
+
+type Heading struct {
+	Level  int
+	Text   string
+	Anchor string
 }
@@ -0,0 +1,88 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/crewblade/notes_service/internal/lib/caller"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/netip"
+)
+
+// ClientIdHeader carries the client a trusted proxy forwards a call for.
+// It is ignored on calls from any other peer.
+const ClientIdHeader = "x-client-id"
+
+// Caller puts the identity of whoever issued a call in its context. That is
+// the subject of a verified client certificate if there is one, else the
+// peer host. Behind a trusted proxy every call shares the proxy's address,
+// so the client id it forwards is used instead.
+type Caller struct {
+	trustedProxies []netip.Prefix
+}
+
+func NewCaller(trustedProxies []netip.Prefix) *Caller {
+	return &Caller{trustedProxies: trustedProxies}
+}
+
+func (c *Caller) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(c.withCaller(ctx), req)
+	}
+}
+
+func (c *Caller) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: c.withCaller(ss.Context())})
+	}
+}
+
+func (c *Caller) withCaller(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ctx
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+		if name := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName; name != "" {
+			return caller.With(ctx, name)
+		}
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if c.trusted(host) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(ClientIdHeader); len(v) > 0 && v[0] != "" {
+				return caller.With(ctx, v[0])
+			}
+		}
+	}
+	return caller.With(ctx, host)
+}
+
+func (c *Caller) trusted(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextStream replaces the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,43 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/crewblade/notes_service/internal/lib/caller"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestCallerIdentity(t *testing.T) {
+	c := NewCaller([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+	tests := []struct {
+		name     string
+		peer     string
+		clientId string
+		want     string
+	}{
+		{name: "peer address", peer: "192.0.2.7", want: "192.0.2.7"},
+		{name: "client id from untrusted peer", peer: "192.0.2.7", clientId: "spoofed", want: "192.0.2.7"},
+		{name: "client id from trusted proxy", peer: "10.1.2.3", clientId: "client-a", want: "client-a"},
+		{name: "trusted proxy without client id", peer: "10.1.2.3", want: "10.1.2.3"},
+		{name: "ipv4 mapped trusted proxy", peer: "::ffff:10.1.2.3", clientId: "client-a", want: "client-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 50000},
+			})
+			if tt.clientId != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(ClientIdHeader, tt.clientId))
+			}
+
+			got, ok := caller.FromContext(c.withCaller(ctx))
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package interceptors
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionalphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"time"
+)
+
+// unboundedServices hold long lived streams, such as health Watch, that the
+// default timeout would cut off. An override still applies to them.
+var unboundedServices = map[string]bool{
+	healthpb.Health_ServiceDesc.ServiceName:                    true,
+	reflectionpb.ServerReflection_ServiceDesc.ServiceName:      true,
+	reflectionalphapb.ServerReflection_ServiceDesc.ServiceName: true,
+}
+
+// Deadline bounds every call by a server side timeout. A client deadline that
+// is already shorter wins.
+type Deadline struct {
+	timeout   time.Duration
+	overrides map[string]time.Duration
+}
+
+// NewDeadline applies timeout to every method except those in overrides,
+// which are keyed by full method name ("/notes.Notes/UploadAttachment").
+// A zero timeout leaves the call unbounded.
+func NewDeadline(timeout time.Duration, overrides map[string]time.Duration) *Deadline {
+	return &Deadline{timeout: timeout, overrides: overrides}
+}
+
+func (d *Deadline) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := d.withTimeout(ctx, info.FullMethod)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+func (d *Deadline) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := d.withTimeout(ss.Context(), info.FullMethod)
+		defer cancel()
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (d *Deadline) withTimeout(ctx context.Context, method string) (context.Context, context.CancelFunc) {
+	timeout, ok := d.overrides[method]
+	if !ok {
+		if unboundedServices[serviceName(method)] {
+			return ctx, func() {}
+		}
+		timeout = d.timeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,329 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/lib/caller"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"hash"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// IdempotencyKeyHeader is the metadata key clients set on mutating calls
+// they may retry.
+const IdempotencyKeyHeader = "idempotency-key"
+
+const maxIdempotencyKeyLen = 255
+
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, callerId, key, method, requestHash string, ttl time.Duration) (record models.IdempotencyRecord, reserved bool, err error)
+	SaveIdempotentResponse(ctx context.Context, callerId, key, requestHash string, response []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, callerId, key string) error
+}
+
+// Idempotency replays the stored response of a mutating call when it is
+// retried with the same idempotency key and request.
+type Idempotency struct {
+	log     *slog.Logger
+	store   IdempotencyStore
+	ttl     time.Duration
+	methods map[string]bool
+}
+
+// NewIdempotency applies to the given full method names only.
+func NewIdempotency(log *slog.Logger, store IdempotencyStore, ttl time.Duration, methods []string) *Idempotency {
+	i := &Idempotency{
+		log:     log,
+		store:   store,
+		ttl:     ttl,
+		methods: make(map[string]bool, len(methods)),
+	}
+	for _, m := range methods {
+		i.methods[m] = true
+	}
+	return i
+}
+
+func (i *Idempotency) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key, err := idempotencyKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" || !i.methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		const op = "interceptors.Idempotency.Unary"
+		log := logging.FromContext(ctx, i.log).With(slog.String("op", op), slog.String("method", info.FullMethod))
+
+		h := newRequestHasher()
+		h.add(req.(proto.Message))
+		requestHash := h.sum()
+
+		// Keys are picked by clients, so they are only unique per caller.
+		callerId, _ := caller.FromContext(ctx)
+		record, reserved, err := i.store.ReserveIdempotencyKey(ctx, callerId, key, info.FullMethod, requestHash, i.ttl)
+		if err != nil {
+			log.Error("failed to reserve idempotency key", slog.String("err", err.Error()))
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+		if !reserved {
+			if err := checkRecord(record, info.FullMethod, requestHash); err != nil {
+				return nil, err
+			}
+			return replay(record)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			i.fail(ctx, log, callerId, key, err)
+			return nil, err
+		}
+		i.save(ctx, log, callerId, key, requestHash, resp.(proto.Message))
+		return resp, nil
+	}
+}
+
+// Stream covers client streaming calls. The request hash is computed over
+// every received message, so a replayed call has to send the whole stream
+// again before it gets the stored response.
+func (i *Idempotency) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		key, err := idempotencyKey(ss.Context())
+		if err != nil {
+			return err
+		}
+		if key == "" || !i.methods[info.FullMethod] || !info.IsClientStream || info.IsServerStream {
+			return handler(srv, ss)
+		}
+		const op = "interceptors.Idempotency.Stream"
+		ctx := ss.Context()
+		log := logging.FromContext(ctx, i.log).With(slog.String("op", op), slog.String("method", info.FullMethod))
+
+		callerId, _ := caller.FromContext(ctx)
+		record, reserved, err := i.store.ReserveIdempotencyKey(ctx, callerId, key, info.FullMethod, "", i.ttl)
+		if err != nil {
+			log.Error("failed to reserve idempotency key", slog.String("err", err.Error()))
+			return status.Error(codes.Internal, "internal error")
+		}
+		if !reserved {
+			if err := checkRecord(record, info.FullMethod, ""); err != nil {
+				return err
+			}
+			requestHash, err := drain(ss, info.FullMethod)
+			if err != nil {
+				return err
+			}
+			if err := checkRecord(record, info.FullMethod, requestHash); err != nil {
+				return err
+			}
+			resp, err := replay(record)
+			if err != nil {
+				return err
+			}
+			return ss.SendMsg(resp)
+		}
+
+		stream := &hashingStream{ServerStream: ss, hasher: newRequestHasher()}
+		if err := handler(srv, stream); err != nil {
+			i.fail(ctx, log, callerId, key, err)
+			return err
+		}
+		i.save(ctx, log, callerId, key, stream.hasher.sum(), stream.resp)
+		return nil
+	}
+}
+
+func (i *Idempotency) save(ctx context.Context, log *slog.Logger, callerId, key, requestHash string, resp proto.Message) {
+	if resp == nil {
+		i.release(ctx, log, callerId, key)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	var data []byte
+	packed, err := anypb.New(resp)
+	if err == nil {
+		data, err = proto.Marshal(packed)
+	}
+	if err == nil {
+		err = i.store.SaveIdempotentResponse(ctx, callerId, key, requestHash, data)
+	}
+	if err != nil {
+		// The call itself succeeded, so the key stays reserved: releasing it
+		// would let a retry apply the write again.
+		log.Error("failed to save idempotent response", slog.String("err", err.Error()))
+	}
+}
+
+// settledCodes are returned by handlers before they write anything, so the
+// request can safely run again under the same key.
+var settledCodes = map[codes.Code]bool{
+	codes.InvalidArgument:    true,
+	codes.NotFound:           true,
+	codes.AlreadyExists:      true,
+	codes.PermissionDenied:   true,
+	codes.ResourceExhausted:  true,
+	codes.FailedPrecondition: true,
+	codes.OutOfRange:         true,
+	codes.Unimplemented:      true,
+	codes.Unauthenticated:    true,
+}
+
+// fail releases the key of a request that failed without writing anything.
+// Any other failure, such as a cancel, a deadline or a connection lost
+// around a commit, may have applied the write, so the reservation is kept
+// and retries are refused until it expires.
+func (i *Idempotency) fail(ctx context.Context, log *slog.Logger, callerId, key string, err error) {
+	if settledCodes[status.Code(err)] {
+		i.release(ctx, log, callerId, key)
+		return
+	}
+	log.Warn("keeping idempotency key of a request with unknown outcome",
+		slog.String("code", status.Code(err).String()),
+	)
+}
+
+func (i *Idempotency) release(ctx context.Context, log *slog.Logger, callerId, key string) {
+	if err := i.store.ReleaseIdempotencyKey(context.WithoutCancel(ctx), callerId, key); err != nil {
+		log.Error("failed to release idempotency key", slog.String("err", err.Error()))
+	}
+}
+
+func idempotencyKey(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	values := md.Get(IdempotencyKeyHeader)
+	if len(values) == 0 {
+		return "", nil
+	}
+	key := strings.TrimSpace(values[0])
+	if len(values) > 1 || key == "" || len(key) > maxIdempotencyKeyLen {
+		return "", status.Error(codes.InvalidArgument, fmt.Sprintf("%s must be a single value of 1 to %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLen))
+	}
+	return key, nil
+}
+
+// checkRecord compares a stored record with a retried request. An empty
+// requestHash skips the payload comparison.
+func checkRecord(record models.IdempotencyRecord, method, requestHash string) error {
+	if record.Method != method || (requestHash != "" && record.RequestHash != "" && record.RequestHash != requestHash) {
+		return status.Error(codes.FailedPrecondition, "idempotency key was already used for a different request")
+	}
+	if record.Response == nil {
+		return status.Error(codes.Aborted, "a request with this idempotency key is in progress or ended with an unknown outcome")
+	}
+	return nil
+}
+
+func replay(record models.IdempotencyRecord) (proto.Message, error) {
+	var packed anypb.Any
+	if err := proto.Unmarshal(record.Response, &packed); err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	resp, err := packed.UnmarshalNew()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return resp, nil
+}
+
+// drain reads the rest of a client stream to hash it.
+func drain(ss grpc.ServerStream, fullMethod string) (string, error) {
+	msgType, err := requestType(fullMethod)
+	if err != nil {
+		return "", status.Error(codes.Internal, "internal error")
+	}
+	h := newRequestHasher()
+	for {
+		msg := msgType.New().Interface()
+		err := ss.RecvMsg(msg)
+		if errors.Is(err, io.EOF) {
+			return h.sum(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		h.add(msg)
+	}
+}
+
+func requestType(fullMethod string) (protoreflect.MessageType, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid method %q", fullMethod)
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, err
+	}
+	serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(method))
+	if methodDesc == nil {
+		return nil, fmt.Errorf("unknown method %q", fullMethod)
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(methodDesc.Input().FullName())
+}
+
+// requestHasher hashes a sequence of messages in their deterministic encoding.
+type requestHasher struct {
+	h hash.Hash
+}
+
+func newRequestHasher() *requestHasher {
+	return &requestHasher{h: sha256.New()}
+}
+
+func (r *requestHasher) add(m proto.Message) {
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	var size [8]byte
+	binary.BigEndian.PutUint64(size[:], uint64(len(data)))
+	r.h.Write(size[:])
+	r.h.Write(data)
+}
+
+func (r *requestHasher) sum() string {
+	return hex.EncodeToString(r.h.Sum(nil))
+}
+
+type hashingStream struct {
+	grpc.ServerStream
+	hasher *requestHasher
+	resp   proto.Message
+}
+
+func (s *hashingStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.hasher.add(m.(proto.Message))
+	}
+	return err
+}
+
+func (s *hashingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.resp = m.(proto.Message)
+	}
+	return err
+}
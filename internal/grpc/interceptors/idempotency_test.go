@@ -0,0 +1,83 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyStore reserves every key it has not seen and records
+// which ones were released.
+type memoryIdempotencyStore struct {
+	reserved map[string]bool
+	released []string
+}
+
+func (s *memoryIdempotencyStore) ReserveIdempotencyKey(_ context.Context, callerId, key, method, requestHash string, _ time.Duration) (models.IdempotencyRecord, bool, error) {
+	if s.reserved[callerId+"|"+key] {
+		return models.IdempotencyRecord{CallerId: callerId, Key: key, Method: method, RequestHash: requestHash}, false, nil
+	}
+	s.reserved[callerId+"|"+key] = true
+	return models.IdempotencyRecord{}, true, nil
+}
+
+func (s *memoryIdempotencyStore) SaveIdempotentResponse(context.Context, string, string, string, []byte) error {
+	return nil
+}
+
+func (s *memoryIdempotencyStore) ReleaseIdempotencyKey(_ context.Context, callerId, key string) error {
+	delete(s.reserved, callerId+"|"+key)
+	s.released = append(s.released, key)
+	return nil
+}
+
+func TestIdempotencyKeepsKeysOfUnknownOutcomes(t *testing.T) {
+	const method = "/notes.Notes/CreateNote"
+	tests := []struct {
+		name     string
+		err      error
+		released bool
+	}{
+		{name: "invalid argument", err: status.Error(codes.InvalidArgument, "title is required"), released: true},
+		{name: "already exists", err: status.Error(codes.AlreadyExists, "id already exists"), released: true},
+		{name: "deadline exceeded", err: status.Error(codes.DeadlineExceeded, "deadline exceeded")},
+		{name: "canceled", err: status.Error(codes.Canceled, "request canceled")},
+		{name: "internal", err: status.Error(codes.Internal, "internal error")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryIdempotencyStore{reserved: make(map[string]bool)}
+			unary := NewIdempotency(slog.New(slog.NewTextHandler(io.Discard, nil)), store, time.Hour, []string{method}).Unary()
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(IdempotencyKeyHeader, "key-1"))
+			info := &grpc.UnaryServerInfo{FullMethod: method}
+			calls := 0
+			handler := func(context.Context, any) (any, error) {
+				calls++
+				return nil, tt.err
+			}
+
+			_, err := unary(ctx, &emptypb.Empty{}, info, handler)
+			assert.Equal(t, tt.err, err)
+			_, err = unary(ctx, &emptypb.Empty{}, info, handler)
+
+			if tt.released {
+				assert.Equal(t, []string{"key-1", "key-1"}, store.released)
+				assert.Equal(t, 2, calls)
+			} else {
+				// The retry must not run the handler again.
+				assert.Empty(t, store.released)
+				assert.Equal(t, 1, calls)
+				assert.Equal(t, codes.Aborted, status.Code(err))
+			}
+		})
+	}
+}
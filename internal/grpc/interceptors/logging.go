@@ -0,0 +1,128 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/crewblade/notes_service/internal/lib/caller"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"github.com/crewblade/notes_service/internal/lib/requestid"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// RequestIdHeader carries the request id in both directions. Ids sent by
+// clients are kept so a request can be followed across services.
+const RequestIdHeader = "x-request-id"
+
+// maxRequestIdLength keeps client supplied ids from bloating every log line.
+const maxRequestIdLength = 128
+
+// Logging gives every call a request id and a logger carrying it, and logs
+// the outcome of the call once it is done. Requests and responses are never
+// logged, as they hold note content.
+type Logging struct {
+	log *slog.Logger
+}
+
+func NewLogging(log *slog.Logger) *Logging {
+	return &Logging{log: log}
+}
+
+func (l *Logging) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, log := l.start(ctx, info.FullMethod)
+		if id, ok := requestid.FromContext(ctx); ok {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIdHeader, id))
+		}
+		resp, err := handler(ctx, req)
+		l.finish(ctx, log, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func (l *Logging) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, log := l.start(ss.Context(), info.FullMethod)
+		if id, ok := requestid.FromContext(ctx); ok {
+			_ = ss.SetHeader(metadata.Pairs(RequestIdHeader, id))
+		}
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		l.finish(ctx, log, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (l *Logging) start(ctx context.Context, method string) (context.Context, *slog.Logger) {
+	id := incomingRequestId(ctx)
+	if id == "" {
+		id = uuid.NewString()
+	}
+	ctx = requestid.With(ctx, id)
+	log := l.log.With(slog.String("request_id", id), slog.String("method", method))
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		log = log.With(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return logging.With(ctx, log), log
+}
+
+func (l *Logging) finish(ctx context.Context, log *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	if id, ok := caller.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("caller", id))
+	}
+	level := slog.LevelInfo
+	if healthpb.Health_ServiceDesc.ServiceName == serviceName(method) {
+		// Probes arrive every few seconds and would drown everything else.
+		level = slog.LevelDebug
+	}
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		level = slog.LevelWarn
+	}
+	log.LogAttrs(ctx, level, "finished call", attrs...)
+}
+
+// serviceName returns "pkg.Service" from "/pkg.Service/Method".
+func serviceName(method string) string {
+	method = strings.TrimPrefix(method, "/")
+	if i := strings.LastIndex(method, "/"); i >= 0 {
+		return method[:i]
+	}
+	return method
+}
+
+func incomingRequestId(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	v := md.Get(RequestIdHeader)
+	if len(v) == 0 || len(v[0]) > maxRequestIdLength {
+		return ""
+	}
+	for _, r := range v[0] {
+		if r < 0x21 || r > 0x7e {
+			return ""
+		}
+	}
+	return v[0]
+}
@@ -0,0 +1,94 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	const (
+		getNoteById = "/notes.Notes/GetNoteById"
+		deleteNote  = "/notes.Notes/DeleteNote"
+	)
+	notFound := status.Error(codes.NotFound, "id not found")
+
+	kinds := []struct {
+		name string
+		call func(m *Metrics, method string, err error) error
+	}{
+		{
+			name: "unary",
+			call: func(m *Metrics, method string, err error) error {
+				_, err = m.Unary()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+					func(context.Context, any) (any, error) { return nil, err })
+				return err
+			},
+		},
+		{
+			name: "stream",
+			call: func(m *Metrics, method string, err error) error {
+				return m.Stream()(nil, nil, &grpc.StreamServerInfo{FullMethod: method},
+					func(any, grpc.ServerStream) error { return err })
+			},
+		},
+	}
+	for _, kind := range kinds {
+		t.Run(kind.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			m := NewMetrics(reg)
+
+			calls := []struct {
+				method string
+				err    error
+			}{
+				{method: getNoteById},
+				{method: getNoteById},
+				{method: getNoteById, err: notFound},
+				{method: deleteNote, err: notFound},
+			}
+			for _, c := range calls {
+				assert.Equal(t, c.err, kind.call(m, c.method, c.err))
+			}
+
+			require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP grpc_server_handled_total RPCs completed on the server, regardless of success or failure.
+# TYPE grpc_server_handled_total counter
+grpc_server_handled_total{code="NotFound",method="/notes.Notes/DeleteNote"} 1
+grpc_server_handled_total{code="NotFound",method="/notes.Notes/GetNoteById"} 1
+grpc_server_handled_total{code="OK",method="/notes.Notes/GetNoteById"} 2
+`), "grpc_server_handled_total"))
+
+			// Latency is observed once per call, whatever its outcome.
+			assert.Equal(t, map[string]uint64{getNoteById: 3, deleteNote: 1}, observations(t, reg, "grpc_server_handling_seconds"))
+		})
+	}
+}
+
+// observations returns the sample count of histogram name per method label.
+func observations(t *testing.T, reg *prometheus.Registry, name string) map[string]uint64 {
+	t.Helper()
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	counts := make(map[string]uint64)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "method" {
+					counts[label.GetValue()] = metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,97 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/crewblade/notes_service/internal/domain/errs"
+	notesrpc "github.com/crewblade/notes_service/internal/grpc/notes"
+	"github.com/crewblade/notes_service/internal/lib/caller"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"github.com/crewblade/notes_service/internal/lib/ratelimit"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log/slog"
+	"time"
+)
+
+// RateLimitStore keeps token buckets. The in-memory store limits each
+// replica on its own; a shared store limits all replicas together.
+type RateLimitStore interface {
+	TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// RateLimit gives every caller a token bucket per method with its own limit
+// and one shared by all other methods. Health checks are never limited.
+type RateLimit struct {
+	log          *slog.Logger
+	store        RateLimitStore
+	defaultLimit ratelimit.Limit
+	methods      map[string]ratelimit.Limit
+}
+
+// NewRateLimit limits methods by their full name, and all others by
+// defaultLimit.
+func NewRateLimit(log *slog.Logger, store RateLimitStore, defaultLimit ratelimit.Limit, methods map[string]ratelimit.Limit) *RateLimit {
+	return &RateLimit{
+		log:          log,
+		store:        store,
+		defaultLimit: defaultLimit,
+		methods:      methods,
+	}
+}
+
+func (r *RateLimit) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := r.take(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (r *RateLimit) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := r.take(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (r *RateLimit) take(ctx context.Context, method string) error {
+	const op = "interceptors.RateLimit"
+	if serviceName(method) == healthpb.Health_ServiceDesc.ServiceName {
+		return nil
+	}
+	limit, bucket := r.defaultLimit, "*"
+	if l, ok := r.methods[method]; ok {
+		limit, bucket = l, method
+	}
+	if limit.Unlimited() {
+		return nil
+	}
+	principal, ok := caller.FromContext(ctx)
+	if !ok {
+		principal = "unknown"
+	}
+
+	allowed, retryAfter, err := r.store.TakeRateLimitToken(ctx, principal+"|"+bucket, limit)
+	if err != nil {
+		// A broken limiter must not take the service down with it.
+		logging.FromContext(ctx, r.log).Warn("rate limiter unavailable, allowing call",
+			slog.String("op", op),
+			slog.String("err", err.Error()),
+		)
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+	logging.FromContext(ctx, r.log).Info("rate limited",
+		slog.String("op", op),
+		slog.String("principal", principal),
+		slog.Duration("retry_after", retryAfter),
+	)
+	e := errs.RateLimited(retryAfter)
+	e.Metadata = map[string]string{"method": method}
+	return notesrpc.ToStatus(e)
+}
@@ -0,0 +1,55 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/crewblade/notes_service/internal/lib/caller"
+	"github.com/crewblade/notes_service/internal/lib/fakes"
+	"github.com/crewblade/notes_service/internal/lib/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRateLimitExhausted(t *testing.T) {
+	clock := fakes.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	unary := NewRateLimit(log, ratelimit.NewMemory(clock), ratelimit.Limit{Rate: 1, Burst: 1}, nil).Unary()
+	info := &grpc.UnaryServerInfo{FullMethod: "/notes.Notes/GetNoteById"}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	call := func(principal string) error {
+		_, err := unary(caller.With(context.Background(), principal), nil, info, handler)
+		return err
+	}
+
+	require.NoError(t, call("client-a"))
+	err := call("client-a")
+	// Other callers have buckets of their own.
+	require.NoError(t, call("client-b"))
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	var (
+		reason     string
+		retryDelay time.Duration
+	)
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			reason = d.GetReason()
+		case *errdetails.RetryInfo:
+			retryDelay = d.GetRetryDelay().AsDuration()
+		}
+	}
+	assert.Equal(t, "RATE_LIMITED", reason)
+	assert.Equal(t, time.Second, retryDelay)
+
+	clock.Advance(time.Second)
+	assert.NoError(t, call("client-a"))
+}
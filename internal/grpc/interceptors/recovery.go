@@ -0,0 +1,75 @@
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"runtime/debug"
+)
+
+// Recovery turns a panic in a handler into an Internal error for that call
+// only, instead of a crash that drops every connection.
+type Recovery struct {
+	log    *slog.Logger
+	panics *prometheus.CounterVec
+	// repanic crashes after logging, so panics are not missed in development.
+	repanic bool
+}
+
+func NewRecovery(log *slog.Logger, reg prometheus.Registerer, repanic bool) *Recovery {
+	r := &Recovery{
+		log: log,
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "grpc",
+			Subsystem: "server",
+			Name:      "panics_total",
+			Help:      "Panics recovered in RPC handlers.",
+		}, []string{"method"}),
+		repanic: repanic,
+	}
+	reg.MustRegister(r.panics)
+	return r
+}
+
+func (r *Recovery) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = r.recovered(ctx, info.FullMethod, p)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func (r *Recovery) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = r.recovered(ss.Context(), info.FullMethod, p)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func (r *Recovery) recovered(ctx context.Context, method string, p any) error {
+	const op = "interceptors.Recovery"
+	r.panics.WithLabelValues(method).Inc()
+	attrs := []any{
+		slog.String("op", op),
+		slog.String("panic", fmt.Sprint(p)),
+		slog.String("stack", string(debug.Stack())),
+	}
+	// The request logger carries the request id.
+	logging.FromContext(ctx, r.log).Error("recovered from panic", attrs...)
+	if r.repanic {
+		panic(p)
+	}
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,127 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"github.com/crewblade/notes_service/internal/domain/errs"
+	"github.com/crewblade/notes_service/internal/domain/models"
+	pb "github.com/crewblade/notes_service/protos/gen/go/notes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+)
+
+type Attachments interface {
+	UploadAttachment(ctx context.Context, noteId, fileName, mimeType string, r io.Reader) (attachment models.Attachment, err error)
+	DownloadAttachment(ctx context.Context, id string) (attachment models.Attachment, content io.ReadCloser, err error)
+	ListAttachments(ctx context.Context, noteId string) (attachments []models.Attachment, err error)
+	DeleteAttachment(ctx context.Context, id string) (attachment models.Attachment, err error)
+}
+
+// downloadChunkSize stays well below the default 4MB gRPC message limit.
+const downloadChunkSize = 64 * 1024
+
+func (s *serverAPI) UploadAttachment(stream pb.Notes_UploadAttachmentServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return ToStatus(errs.Invalid("info", "is required"))
+		}
+		return err
+	}
+	info := req.GetInfo()
+	if info == nil {
+		return ToStatus(errs.Invalid("info", "must be sent first"))
+	}
+
+	attachment, err := s.attachments.UploadAttachment(
+		stream.Context(), info.GetNoteId(), info.GetFileName(), info.GetMimeType(), &uploadReader{stream: stream},
+	)
+	if err != nil {
+		return ToStatus(err)
+	}
+	return stream.SendAndClose(attachmentToProto(attachment))
+}
+
+func (s *serverAPI) DownloadAttachment(req *pb.DownloadAttachmentRequest, stream pb.Notes_DownloadAttachmentServer) error {
+	attachment, content, err := s.attachments.DownloadAttachment(stream.Context(), req.GetId())
+	if err != nil {
+		return ToStatus(err)
+	}
+	defer content.Close()
+
+	err = stream.Send(&pb.DownloadAttachmentResponse{
+		Payload: &pb.DownloadAttachmentResponse_Attachment{Attachment: attachmentToProto(attachment)},
+	})
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, downloadChunkSize)
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			sendErr := stream.Send(&pb.DownloadAttachmentResponse{
+				Payload: &pb.DownloadAttachmentResponse_Chunk{Chunk: buf[:n]},
+			})
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return ToStatus(err)
+		}
+	}
+}
+
+func (s *serverAPI) ListAttachments(ctx context.Context, req *pb.ListAttachmentsRequest) (*pb.ListAttachmentsResponse, error) {
+	attachmentsData, err := s.attachments.ListAttachments(ctx, req.GetNoteId())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+	resp := &pb.ListAttachmentsResponse{}
+	for _, attachment := range attachmentsData {
+		resp.Attachments = append(resp.Attachments, attachmentToProto(attachment))
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) DeleteAttachment(ctx context.Context, req *pb.DeleteAttachmentRequest) (*pb.Attachment, error) {
+	attachment, err := s.attachments.DeleteAttachment(ctx, req.GetId())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+	return attachmentToProto(attachment), nil
+}
+
+func attachmentToProto(a models.Attachment) *pb.Attachment {
+	return &pb.Attachment{
+		Id:        a.Id,
+		NoteId:    a.NoteId,
+		FileName:  a.FileName,
+		MimeType:  a.MimeType,
+		Size:      a.Size,
+		Sha256:    a.Sha256,
+		CreatedAt: timestamppb.New(a.CreatedAt),
+	}
+}
+
+// uploadReader exposes the chunks of an upload stream as an io.Reader.
+type uploadReader struct {
+	stream pb.Notes_UploadAttachmentServer
+	buf    []byte
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = req.GetChunk()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
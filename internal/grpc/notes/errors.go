@@ -0,0 +1,71 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"github.com/crewblade/notes_service/internal/domain/errs"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain is set on every ErrorInfo detail returned by the service.
+const ErrorDomain = "notes.crewblade.github.com"
+
+var kindCodes = map[errs.Kind]codes.Code{
+	errs.NotFound:         codes.NotFound,
+	errs.InvalidArgument:  codes.InvalidArgument,
+	errs.Conflict:         codes.AlreadyExists,
+	errs.PermissionDenied: codes.PermissionDenied,
+	errs.QuotaExceeded:    codes.ResourceExhausted,
+	errs.Unavailable:      codes.Unavailable,
+}
+
+// ToStatus converts any error returned by a handler or interceptor into a
+// gRPC status. Domain errors keep their message and gain ErrorInfo,
+// BadRequest and RetryInfo details; everything else becomes a bare Internal
+// error so internals never leak to clients.
+func ToStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+
+	var e *errs.Error
+	if !errors.As(err, &e) || e.Kind == errs.Internal {
+		return status.Error(codes.Internal, "internal error")
+	}
+	st := status.New(kindCodes[e.Kind], e.Message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   e.Reason,
+		Domain:   ErrorDomain,
+		Metadata: e.Metadata,
+	}}
+	if len(e.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range e.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+	if e.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)})
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
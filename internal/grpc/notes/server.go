@@ -2,40 +2,55 @@ package notes
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/errs"
 	"github.com/crewblade/notes_service/internal/domain/models"
-	"github.com/crewblade/notes_service/internal/storage"
 	pb "github.com/crewblade/notes_service/protos/gen/go/notes"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"net/url"
 )
 
 type Notes interface {
-	CreateNote(ctx context.Context, title string, content string) (id string, err error)
+	CreateNote(ctx context.Context, id string, title string, content string, format models.ContentFormat) (noteId string, err error)
+	CreateNoteFromHtml(ctx context.Context, title string, pageHtml string, sourceURL *url.URL) (note models.Note, err error)
 	GetNoteById(ctx context.Context, id string) (note models.Note, err error)
 	GetNotes(ctx context.Context, limit int32, offset_id string) (notes []models.Note, next_offset_id string, err error)
-	UpdateNote(ctx context.Context, id string, title string, content string) (note models.Note, err error)
+	UpdateNote(ctx context.Context, id string, title string, content string, format models.ContentFormat) (note models.Note, err error)
 	DeleteNote(ctx context.Context, id string) (note models.Note, err error)
+	ImportNotes(ctx context.Context, next func() (models.ImportNote, error), dryRun bool) (results []models.ImportResult, err error)
+	RenderNote(ctx context.Context, id string) (note models.Note, rendered models.RenderedContent, err error)
+}
+
+// ServiceName is the name the notes service is registered under.
+var ServiceName = pb.Notes_ServiceDesc.ServiceName
+
+// MutatingMethods lists the calls that change data and honour idempotency keys.
+var MutatingMethods = []string{
+	"/notes.Notes/CreateNote",
+	"/notes.Notes/CreateNoteFromHtml",
+	"/notes.Notes/UpdateNote",
+	"/notes.Notes/DeleteNote",
+	"/notes.Notes/ImportNotes",
+	"/notes.Notes/UploadAttachment",
+	"/notes.Notes/DeleteAttachment",
 }
 
 type serverAPI struct {
 	pb.UnimplementedNotesServer
-	notes Notes
+	notes       Notes
+	attachments Attachments
 }
 
-func Register(gRPC *grpc.Server, notes Notes) {
-	pb.RegisterNotesServer(gRPC, &serverAPI{notes: notes})
+func Register(gRPC *grpc.Server, notes Notes, attachments Attachments) {
+	pb.RegisterNotesServer(gRPC, &serverAPI{notes: notes, attachments: attachments})
 }
 func (s *serverAPI) CreateNote(ctx context.Context, req *pb.CreateNoteRequest) (*pb.CreateNoteResponse, error) {
-	fmt.Println("CreateNode:", ctx, req)
-	if req.GetTitle() == "" {
-		return nil, status.Error(codes.InvalidArgument, "title is required")
+	format, ok := contentFormatFromProto(req.GetContentFormat())
+	if !ok {
+		return nil, ToStatus(errs.Invalid("content_format", "is unknown"))
 	}
-	id, err := s.notes.CreateNote(ctx, req.GetTitle(), req.GetContent())
+	id, err := s.notes.CreateNote(ctx, req.GetId(), req.GetTitle(), req.GetContent(), format)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, ToStatus(err)
 	}
 
 	return &pb.CreateNoteResponse{
@@ -43,45 +58,61 @@ func (s *serverAPI) CreateNote(ctx context.Context, req *pb.CreateNoteRequest) (
 	}, nil
 
 }
-func (s *serverAPI) GetNoteById(ctx context.Context, req *pb.GetNoteByIdRequest) (*pb.Note, error) {
-	if req.GetId() == "" {
-		return nil, status.Error(codes.InvalidArgument, "id is required")
+func (s *serverAPI) CreateNoteFromHtml(ctx context.Context, req *pb.CreateNoteFromHtmlRequest) (*pb.Note, error) {
+	if req.GetHtml() == "" {
+		return nil, ToStatus(errs.Invalid("html", "is required"))
+	}
+	if req.GetSourceUrl() == "" {
+		return nil, ToStatus(errs.Invalid("source_url", "is required"))
+	}
+	sourceURL, err := url.Parse(req.GetSourceUrl())
+	if err != nil || (sourceURL.Scheme != "http" && sourceURL.Scheme != "https") || sourceURL.Host == "" {
+		return nil, ToStatus(errs.Invalid("source_url", "must be an absolute http(s) url"))
+	}
+	note, err := s.notes.CreateNoteFromHtml(ctx, req.GetTitle(), req.GetHtml(), sourceURL)
+	if err != nil {
+		return nil, ToStatus(err)
 	}
+	return &pb.Note{
+		Id:            note.Id,
+		Title:         note.Title,
+		Content:       note.Content,
+		Tags:          note.Tags,
+		ContentFormat: contentFormatToProto(note.ContentFormat),
+		SourceUrl:     note.SourceUrl,
+	}, nil
+}
+
+func (s *serverAPI) GetNoteById(ctx context.Context, req *pb.GetNoteByIdRequest) (*pb.Note, error) {
 	var note models.Note
 	note, err := s.notes.GetNoteById(ctx, req.GetId())
 	if err != nil {
-		if errors.Is(err, storage.IdNotFound) {
-			return nil, status.Error(codes.NotFound, "Id not found")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, ToStatus(err)
 	}
 	return &pb.Note{
-		Id:      note.Id,
-		Title:   note.Title,
-		Content: note.Content,
+		Id:            note.Id,
+		Title:         note.Title,
+		Content:       note.Content,
+		Tags:          note.Tags,
+		ContentFormat: contentFormatToProto(note.ContentFormat),
+		SourceUrl:     note.SourceUrl,
 	}, nil
 }
 func (s *serverAPI) GetNotes(ctx context.Context, req *pb.GetNotesRequest) (*pb.GetNotesResponse, error) {
-	if req.GetOffsetId() == "" {
-		return nil, status.Error(codes.InvalidArgument, "get_offset_id is required")
-	}
-	if req.GetLimit() <= 0 {
-		return nil, status.Error(codes.InvalidArgument, "limit should be > 0")
-	}
 	var notesData []models.Note
 	notesData, next_offset_id, err := s.notes.GetNotes(ctx, req.GetLimit(), req.GetOffsetId())
 	if err != nil {
-		if errors.Is(err, storage.IdNotFound) {
-			return nil, status.Error(codes.NotFound, "Id not found")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, ToStatus(err)
 	}
 	var notes []*pb.Note
 	for _, note := range notesData {
 		notes = append(notes, &pb.Note{
-			Id:      note.Id,
-			Title:   note.Title,
-			Content: note.Content,
+			Id:            note.Id,
+			Title:         note.Title,
+			Content:       note.Content,
+			Tags:          note.Tags,
+			ContentFormat: contentFormatToProto(note.ContentFormat),
+			SourceUrl:     note.SourceUrl,
 		})
 	}
 	return &pb.GetNotesResponse{
@@ -91,39 +122,161 @@ func (s *serverAPI) GetNotes(ctx context.Context, req *pb.GetNotesRequest) (*pb.
 }
 
 func (s *serverAPI) UpdateNote(ctx context.Context, req *pb.UpdateNoteRequest) (*pb.Note, error) {
-	if req.GetId() == "" {
-		return nil, status.Error(codes.InvalidArgument, "id is required")
+	format, ok := contentFormatFromProto(req.GetContentFormat())
+	if !ok {
+		return nil, ToStatus(errs.Invalid("content_format", "is unknown"))
 	}
 	var note models.Note
-	note, err := s.notes.UpdateNote(ctx, req.GetId(), req.GetTitle(), req.GetContent())
+	note, err := s.notes.UpdateNote(ctx, req.GetId(), req.GetTitle(), req.GetContent(), format)
 	if err != nil {
-		if errors.Is(err, storage.IdNotFound) {
-			return nil, status.Error(codes.NotFound, "Id not found")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, ToStatus(err)
 	}
 	return &pb.Note{
-		Id:      note.Id,
-		Title:   note.Title,
-		Content: note.Content,
+		Id:            note.Id,
+		Title:         note.Title,
+		Content:       note.Content,
+		Tags:          note.Tags,
+		ContentFormat: contentFormatToProto(note.ContentFormat),
+		SourceUrl:     note.SourceUrl,
 	}, nil
 
 }
 func (s *serverAPI) DeleteNote(ctx context.Context, req *pb.DeleteNoteRequest) (*pb.Note, error) {
-	if req.GetId() == "" {
-		return nil, status.Error(codes.InvalidArgument, "id is required")
-	}
 	var note models.Note
 	note, err := s.notes.DeleteNote(ctx, req.GetId())
 	if err != nil {
-		if errors.Is(err, storage.IdNotFound) {
-			return nil, status.Error(codes.NotFound, "Id not found")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, ToStatus(err)
 	}
 	return &pb.Note{
-		Id:      note.Id,
-		Title:   note.Title,
-		Content: note.Content,
+		Id:            note.Id,
+		Title:         note.Title,
+		Content:       note.Content,
+		Tags:          note.Tags,
+		ContentFormat: contentFormatToProto(note.ContentFormat),
+		SourceUrl:     note.SourceUrl,
 	}, nil
 }
+
+func (s *serverAPI) ImportNotes(stream pb.Notes_ImportNotesServer) error {
+	// dry_run is read from the first message, before any note is written.
+	first, firstErr := stream.Recv()
+	dryRun := first.GetDryRun()
+	next := func() (models.ImportNote, error) {
+		req, err := first, firstErr
+		if req == nil && err == nil {
+			req, err = stream.Recv()
+		}
+		first, firstErr = nil, nil
+		if err != nil {
+			return models.ImportNote{}, err
+		}
+		return importNoteFromProto(req.GetNote())
+	}
+
+	results, err := s.notes.ImportNotes(stream.Context(), next, dryRun)
+	if err != nil {
+		return ToStatus(err)
+	}
+	resp := &pb.ImportNotesResponse{DryRun: dryRun}
+	for _, result := range results {
+		resp.Results = append(resp.Results, &pb.ImportResult{
+			Source:  result.Source,
+			Id:      result.Id,
+			Status:  importStatusToProto(result.Status),
+			Message: result.Message,
+		})
+		switch result.Status {
+		case models.ImportStatusImported:
+			resp.Imported++
+		case models.ImportStatusDuplicate:
+			resp.Duplicates++
+		case models.ImportStatusFailed:
+			resp.Failed++
+		}
+	}
+	return stream.SendAndClose(resp)
+}
+
+func importNoteFromProto(note *pb.ImportNote) (models.ImportNote, error) {
+	if note == nil {
+		return models.ImportNote{}, errs.Invalid("note", "is required")
+	}
+	format, ok := contentFormatFromProto(note.GetContentFormat())
+	if !ok {
+		return models.ImportNote{}, errs.Invalid("content_format", "is unknown")
+	}
+	importNote := models.ImportNote{
+		Source:  note.GetSource(),
+		Id:      note.GetId(),
+		Title:   note.GetTitle(),
+		Content: note.GetContent(),
+		Format:  format,
+		Tags:    note.GetTags(),
+	}
+	if note.GetCreatedAt() != nil {
+		importNote.CreatedAt = note.GetCreatedAt().AsTime()
+	}
+	if note.GetUpdatedAt() != nil {
+		importNote.UpdatedAt = note.GetUpdatedAt().AsTime()
+	}
+	return importNote, nil
+}
+
+func (s *serverAPI) RenderNote(ctx context.Context, req *pb.RenderNoteRequest) (*pb.RenderNoteResponse, error) {
+	note, rendered, err := s.notes.RenderNote(ctx, req.GetId())
+	if err != nil {
+		return nil, ToStatus(err)
+	}
+	resp := &pb.RenderNoteResponse{
+		Id:            note.Id,
+		ContentFormat: contentFormatToProto(note.ContentFormat),
+		Html:          rendered.HTML,
+	}
+	for _, h := range rendered.Outline {
+		resp.Outline = append(resp.Outline, &pb.Heading{
+			Level:  int32(h.Level),
+			Text:   h.Text,
+			Anchor: h.Anchor,
+		})
+	}
+	return resp, nil
+}
+
+// contentFormatFromProto maps an unspecified format to "", leaving the
+// choice of a default to the service.
+func contentFormatFromProto(f pb.ContentFormat) (models.ContentFormat, bool) {
+	switch f {
+	case pb.ContentFormat_CONTENT_FORMAT_UNSPECIFIED:
+		return "", true
+	case pb.ContentFormat_CONTENT_FORMAT_PLAIN:
+		return models.ContentFormatPlain, true
+	case pb.ContentFormat_CONTENT_FORMAT_MARKDOWN:
+		return models.ContentFormatMarkdown, true
+	default:
+		return "", false
+	}
+}
+
+func contentFormatToProto(f models.ContentFormat) pb.ContentFormat {
+	switch f {
+	case models.ContentFormatPlain:
+		return pb.ContentFormat_CONTENT_FORMAT_PLAIN
+	case models.ContentFormatMarkdown:
+		return pb.ContentFormat_CONTENT_FORMAT_MARKDOWN
+	default:
+		return pb.ContentFormat_CONTENT_FORMAT_UNSPECIFIED
+	}
+}
+
+func importStatusToProto(s models.ImportStatus) pb.ImportStatus {
+	switch s {
+	case models.ImportStatusImported:
+		return pb.ImportStatus_IMPORT_STATUS_IMPORTED
+	case models.ImportStatusDuplicate:
+		return pb.ImportStatus_IMPORT_STATUS_DUPLICATE
+	case models.ImportStatusFailed:
+		return pb.ImportStatus_IMPORT_STATUS_FAILED
+	default:
+		return pb.ImportStatus_IMPORT_STATUS_UNSPECIFIED
+	}
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/markdown"
+	"golang.org/x/net/html"
+)
+
+// enexTimeLayout is the timestamp format used in Evernote exports.
+const enexTimeLayout = "20060102T150405Z"
+
+type enexNote struct {
+	Title     string         `xml:"title"`
+	Content   string         `xml:"content"`
+	Created   string         `xml:"created"`
+	Updated   string         `xml:"updated"`
+	Tags      []string       `xml:"tag"`
+	Resources []enexResource `xml:"resource"`
+}
+
+type enexResource struct {
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+// parseENEX reads an Evernote export. ENML content is converted to Markdown;
+// elements that can not be represented are listed in res.Warnings.
+func parseENEX(name string, r io.Reader, res *Result) error {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	for i := 1; ; {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		var en enexNote
+		if err := d.DecodeElement(&en, &start); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		source := fmt.Sprintf("%s#%d", name, i)
+		i++
+
+		note, warnings, err := convertENEXNote(source, en)
+		if err != nil {
+			res.Failed = append(res.Failed, failure(source, err))
+			continue
+		}
+		res.Notes = append(res.Notes, note)
+		res.warn(source, warnings...)
+	}
+}
+
+func convertENEXNote(source string, en enexNote) (models.ImportNote, []string, error) {
+	note := models.ImportNote{
+		Source: source,
+		Title:  strings.TrimSpace(en.Title),
+		Format: models.ContentFormatMarkdown,
+		Tags:   en.Tags,
+	}
+
+	var err error
+	if note.CreatedAt, err = parseENEXTime(en.Created); err != nil {
+		return note, nil, err
+	}
+	if note.UpdatedAt, err = parseENEXTime(en.Updated); err != nil {
+		return note, nil, err
+	}
+
+	root, err := parseENML(en.Content)
+	if err != nil {
+		return note, nil, fmt.Errorf("invalid note content: %w", err)
+	}
+	var warnings []string
+	rewriteENML(root, &warnings)
+	content, unsupported := markdown.FromHTML(root)
+	note.Content = content
+	for _, name := range unsupported {
+		warnings = append(warnings, "<"+name+">")
+	}
+
+	// resources without an en-media reference are not visible in the content
+	if len(en.Resources) > 0 && !containsPrefix(warnings, "en-media") {
+		for _, r := range en.Resources {
+			warnings = append(warnings, "resource "+describeResource(r))
+		}
+	}
+	return note, warnings, nil
+}
+
+func parseENEXTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(enexTimeLayout, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", v)
+	}
+	return t, nil
+}
+
+// parseENML builds an HTML tree from ENML. ENML is XHTML, so it is read with
+// the XML decoder; the HTML parser would mishandle self-closing en-* elements.
+func parseENML(content string) (*html.Node, error) {
+	d := xml.NewDecoder(strings.NewReader(content))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	root := &html.Node{Type: html.DocumentNode}
+	cur := root
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &html.Node{Type: html.ElementNode, Data: strings.ToLower(t.Name.Local)}
+			for _, a := range t.Attr {
+				n.Attr = append(n.Attr, html.Attribute{Key: strings.ToLower(a.Name.Local), Val: a.Value})
+			}
+			cur.AppendChild(n)
+			cur = n
+		case xml.EndElement:
+			if cur.Parent != nil {
+				cur = cur.Parent
+			}
+		case xml.CharData:
+			cur.AppendChild(&html.Node{Type: html.TextNode, Data: string(t)})
+		}
+	}
+}
+
+// rewriteENML replaces Evernote specific elements with their HTML
+// equivalents, removing and reporting the ones that have none.
+func rewriteENML(n *html.Node, warnings *[]string) {
+	for ch := n.FirstChild; ch != nil; {
+		next := ch.NextSibling
+		if ch.Type == html.ElementNode {
+			switch ch.Data {
+			case "en-note":
+				ch.Data = "div"
+			case "en-todo":
+				ch.Data = "input"
+				checked := attrValue(ch, "checked") == "true"
+				ch.Attr = []html.Attribute{{Key: "type", Val: "checkbox"}}
+				if checked {
+					ch.Attr = append(ch.Attr, html.Attribute{Key: "checked"})
+				}
+			case "en-media":
+				*warnings = append(*warnings, "en-media ("+attrValue(ch, "type")+")")
+				n.RemoveChild(ch)
+			case "en-crypt":
+				*warnings = append(*warnings, "en-crypt (encrypted text)")
+				n.RemoveChild(ch)
+			}
+		}
+		if ch.Parent == n {
+			rewriteENML(ch, warnings)
+		}
+		ch = next
+	}
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func describeResource(r enexResource) string {
+	if r.FileName != "" {
+		return r.FileName + " (" + r.Mime + ")"
+	}
+	return "(" + r.Mime + ")"
+}
+
+func containsPrefix(list []string, prefix string) bool {
+	for _, s := range list {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/crewblade/notes_service/internal/domain/models"
+)
+
+type Format string
+
+const (
+	FormatAuto     Format = "auto"
+	FormatMarkdown Format = "markdown"
+	FormatJSONL    Format = "jsonl"
+	FormatENEX     Format = "enex"
+)
+
+// Result holds the notes parsed from an export and the entries that
+// could not be parsed, reported as failed import results.
+// Warnings lists, by source, content that was lost during conversion.
+type Result struct {
+	Notes    []models.ImportNote
+	Failed   []models.ImportResult
+	Warnings map[string][]string
+}
+
+func (r *Result) warn(source string, warnings ...string) {
+	if len(warnings) == 0 {
+		return
+	}
+	if r.Warnings == nil {
+		r.Warnings = make(map[string][]string)
+	}
+	r.Warnings[source] = append(r.Warnings[source], warnings...)
+}
+
+// Read parses an export located at path. It accepts a directory, a zip or
+// tar (optionally gzipped) archive, or a single Markdown, JSONL or ENEX file.
+// With FormatAuto the format of every file is picked by its extension.
+func Read(path string, format Format) (*Result, error) {
+	const op = "importer.Read"
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	res := &Result{}
+	switch {
+	case info.IsDir():
+		err = readDir(path, format, res)
+	case strings.HasSuffix(path, ".zip"):
+		err = readZip(path, format, res)
+	case strings.HasSuffix(path, ".tar"), strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		err = readTar(path, format, res)
+	default:
+		err = readFile(path, path, format, res)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return res, nil
+}
+
+func readDir(root string, format Format, res *Result) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return readFile(filepath.ToSlash(name), path, format, res)
+	})
+}
+
+func readFile(name, path string, format Format, res *Result) error {
+	if !supported(name, format) {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parse(name, f, format, res)
+}
+
+func readZip(path string, format Format, res *Result) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !supported(f.Name, format) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		err = parse(f.Name, rc, format, res)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTar(name string, format Format, res *Result) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(name, ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || !supported(hdr.Name, format) {
+			continue
+		}
+		if err := parse(path.Clean(hdr.Name), tr, format, res); err != nil {
+			return err
+		}
+	}
+}
+
+func parse(name string, r io.Reader, format Format, res *Result) error {
+	switch formatOf(name, format) {
+	case FormatMarkdown:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		note, err := parseMarkdown(name, data)
+		if err != nil {
+			res.Failed = append(res.Failed, failure(name, err))
+			return nil
+		}
+		res.Notes = append(res.Notes, note)
+	case FormatJSONL:
+		return parseJSONL(name, r, res)
+	case FormatENEX:
+		return parseENEX(name, r, res)
+	}
+	return nil
+}
+
+func supported(name string, format Format) bool {
+	if strings.HasPrefix(filepath.Base(name), ".") {
+		return false
+	}
+	return formatOf(name, format) != ""
+}
+
+func formatOf(name string, format Format) Format {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case (format == FormatAuto || format == FormatMarkdown) && (ext == ".md" || ext == ".markdown"):
+		return FormatMarkdown
+	case (format == FormatAuto || format == FormatJSONL) && (ext == ".jsonl" || ext == ".ndjson"):
+		return FormatJSONL
+	case (format == FormatAuto || format == FormatENEX) && ext == ".enex":
+		return FormatENEX
+	}
+	return ""
+}
+
+func failure(source string, err error) models.ImportResult {
+	return models.ImportResult{
+		Source:  source,
+		Status:  models.ImportStatusFailed,
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/crewblade/notes_service/internal/domain/models"
+)
+
+type jsonNote struct {
+	Id            string   `json:"id"`
+	Title         string   `json:"title"`
+	Content       string   `json:"content"`
+	ContentFormat string   `json:"content_format"`
+	Tags          []string `json:"tags"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
+}
+
+const maxJSONLineSize = 16 << 20
+
+// parseJSONL reads one note per line. Lines are reported as "name:line".
+func parseJSONL(name string, r io.Reader, res *Result) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxJSONLineSize)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", name, line)
+
+		var jn jsonNote
+		if err := json.Unmarshal([]byte(text), &jn); err != nil {
+			res.Failed = append(res.Failed, failure(source, fmt.Errorf("invalid json: %w", err)))
+			continue
+		}
+		note := models.ImportNote{
+			Source:  source,
+			Id:      jn.Id,
+			Title:   jn.Title,
+			Content: jn.Content,
+			Tags:    jn.Tags,
+		}
+		switch format := models.ContentFormat(jn.ContentFormat); format {
+		case "", models.ContentFormatPlain, models.ContentFormatMarkdown:
+			note.Format = format
+		default:
+			res.Failed = append(res.Failed, failure(source, fmt.Errorf("unknown content_format %q", format)))
+			continue
+		}
+		var err error
+		if note.CreatedAt, err = firstTime(jn.CreatedAt); err != nil {
+			res.Failed = append(res.Failed, failure(source, err))
+			continue
+		}
+		if note.UpdatedAt, err = firstTime(jn.UpdatedAt); err != nil {
+			res.Failed = append(res.Failed, failure(source, err))
+			continue
+		}
+		res.Notes = append(res.Notes, note)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"gopkg.in/yaml.v3"
+)
+
+var frontMatterDelim = []byte("---")
+
+type frontMatter struct {
+	Id        string     `yaml:"id"`
+	Title     string     `yaml:"title"`
+	Tags      stringList `yaml:"tags"`
+	Created   string     `yaml:"created"`
+	CreatedAt string     `yaml:"created_at"`
+	Date      string     `yaml:"date"`
+	Updated   string     `yaml:"updated"`
+	UpdatedAt string     `yaml:"updated_at"`
+}
+
+// stringList accepts both a YAML sequence and a comma or space separated
+// string, as Obsidian writes either form for tags.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = strings.FieldsFunc(value.Value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// parseMarkdown reads a Markdown note with optional YAML front matter.
+// The title is taken from the front matter, then from the first level one
+// heading, and falls back to the file name.
+func parseMarkdown(name string, data []byte) (models.ImportNote, error) {
+	note := models.ImportNote{Source: name, Format: models.ContentFormatMarkdown}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	body := data
+	var fm frontMatter
+	if hasFrontMatter(data) {
+		rest := data[len(frontMatterDelim):]
+		end := bytes.Index(rest, append([]byte("\n"), frontMatterDelim...))
+		if end < 0 {
+			return note, fmt.Errorf("unterminated front matter")
+		}
+		if err := yaml.Unmarshal(rest[:end], &fm); err != nil {
+			return note, fmt.Errorf("invalid front matter: %w", err)
+		}
+		body = rest[end+1+len(frontMatterDelim):]
+		if i := bytes.IndexByte(body, '\n'); i >= 0 {
+			body = body[i+1:]
+		} else {
+			body = nil
+		}
+	}
+
+	note.Id = fm.Id
+	note.Tags = fm.Tags
+	note.Content = strings.TrimLeft(string(body), "\r\n")
+	note.Title = fm.Title
+	if note.Title == "" {
+		note.Title = firstHeading(note.Content)
+	}
+	if note.Title == "" {
+		note.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+
+	var err error
+	if note.CreatedAt, err = firstTime(fm.CreatedAt, fm.Created, fm.Date); err != nil {
+		return note, err
+	}
+	if note.UpdatedAt, err = firstTime(fm.UpdatedAt, fm.Updated); err != nil {
+		return note, err
+	}
+	return note, nil
+}
+
+func firstTime(values ...string) (time.Time, error) {
+	for _, v := range values {
+		if v != "" {
+			return parseTime(v)
+		}
+	}
+	return time.Time{}, nil
+}
+
+func hasFrontMatter(data []byte) bool {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	return bytes.Equal(bytes.TrimRight(line, "\r"), frontMatterDelim)
+}
+
+func firstHeading(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(line[2:])
+		}
+	}
+	return ""
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+func parseTime(v string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", v)
+}
@@ -0,0 +1,16 @@
+// Package caller carries the identity of whoever issued a request through its
+// context, so lower layers can keep per-caller state without knowing about gRPC.
+package caller
+
+import "context"
+
+type ctxKey struct{}
+
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
@@ -0,0 +1,67 @@
+// Package fakes holds deterministic stand-ins for the clock and id
+// generator, for tests that check timestamps, ordering or pagination.
+package fakes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock returns a fixed time that only changes through Set and Advance.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// IDGenerator returns sequential UUIDs: 00000000-0000-7000-8000-000000000001,
+// ...0002 and so on, so ids sort in creation order like UUIDv7.
+type IDGenerator struct {
+	mu   sync.Mutex
+	next uint64
+	err  error
+}
+
+func NewIDGenerator() *IDGenerator {
+	return &IDGenerator{next: 1}
+}
+
+func (g *IDGenerator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return "", g.err
+	}
+	id := fmt.Sprintf("00000000-0000-7000-8000-%012x", g.next)
+	g.next++
+	return id, nil
+}
+
+// Fail makes every following NewID call return err.
+func (g *IDGenerator) Fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.err = err
+}
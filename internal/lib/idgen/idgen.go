@@ -0,0 +1,15 @@
+package idgen
+
+import "github.com/google/uuid"
+
+// UUIDv7 generates time ordered UUIDs, which keep primary key inserts
+// local in the index and sort by creation time.
+type UUIDv7 struct{}
+
+func (UUIDv7) NewID() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
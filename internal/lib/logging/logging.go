@@ -0,0 +1,49 @@
+// Package logging builds the application logger and carries a request scoped
+// logger through contexts, so every line logged while serving a request can
+// be tied back to it.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New returns a logger writing to w. level is one of debug, info, warn and
+// error; format is text or json.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	const op = "logging.New"
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown format %q", op, format)
+	}
+}
+
+type ctxKey struct{}
+
+func With(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the request logger, or fallback outside of a request.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return log
+	}
+	return fallback
+}
@@ -0,0 +1,107 @@
+// Package ratelimit implements token buckets. A bucket holds up to Burst
+// tokens, refills at Rate tokens per second and every call takes one.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type Limit struct {
+	// Rate is in tokens per second; zero or less means unlimited.
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// Capacity is Burst, but at least one token so a call can ever pass.
+func (l Limit) Capacity() float64 {
+	return math.Max(1, float64(l.Burst))
+}
+
+// Refill returns the tokens of a bucket that held tokens elapsed ago.
+func (l Limit) Refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(l.Capacity(), tokens+elapsed.Seconds()*l.Rate)
+}
+
+// Wait returns how long a bucket holding tokens needs to refill one token.
+func (l Limit) Wait(tokens float64) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - tokens) / l.Rate * float64(time.Second)))
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+// pruneInterval is how often Memory drops buckets that have refilled;
+// a full bucket behaves exactly like a missing one.
+const pruneInterval = time.Minute
+
+// Memory keeps buckets in process. Every replica limits on its own, so the
+// effective limit grows with the number of replicas.
+type Memory struct {
+	clock Clock
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+func NewMemory(clock Clock) *Memory {
+	return &Memory{
+		clock:     clock,
+		buckets:   make(map[string]*bucket),
+		lastPrune: clock.Now(),
+	}
+}
+
+// TakeRateLimitToken takes a token from the bucket under key. When none is left it reports
+// how long until one is.
+func (m *Memory) TakeRateLimitToken(_ context.Context, key string, limit Limit) (allowed bool, retryAfter time.Duration, err error) {
+	now := m.clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastPrune) >= pruneInterval {
+		m.prune(now)
+	}
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.Capacity(), updated: now}
+		m.buckets[key] = b
+	}
+	b.tokens = limit.Refill(b.tokens, now.Sub(b.updated))
+	b.updated = now
+	b.limit = limit
+	if b.tokens < 1 {
+		return false, limit.Wait(b.tokens), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+func (m *Memory) prune(now time.Time) {
+	for key, b := range m.buckets {
+		if b.limit.Refill(b.tokens, now.Sub(b.updated)) >= b.limit.Capacity() {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastPrune = now
+}
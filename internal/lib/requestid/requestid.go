@@ -0,0 +1,16 @@
+// Package requestid carries the id that ties together everything done while
+// serving one request.
+package requestid
+
+import "context"
+
+type ctxKey struct{}
+
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
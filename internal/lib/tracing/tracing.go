@@ -0,0 +1,100 @@
+// Package tracing installs the OpenTelemetry tracer provider and W3C trace
+// context propagation.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"io"
+	"os"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+type Options struct {
+	ServiceName string
+	// Exporter is none, otlp or stdout.
+	Exporter string
+	// Endpoint is the OTLP gRPC collector address, host:port.
+	Endpoint string
+	Insecure bool
+	// File receives stdout exporter output instead of stdout.
+	File string
+	// SampleRatio is the share of new traces recorded; calls that arrive
+	// with a sampled parent are always recorded.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider. The returned function flushes
+// pending spans and must be called before exit. Propagation is installed
+// even without an exporter, so trace context still passes through.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	const op = "tracing.Setup"
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	closeOutput := func() error { return nil }
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, clientOpts...)
+	case ExporterStdout:
+		var w io.Writer = os.Stdout
+		if opts.File != "" {
+			f, ferr := os.OpenFile(opts.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if ferr != nil {
+				return nil, fmt.Errorf("%s: %w", op, ferr)
+			}
+			w, closeOutput = f, f.Close
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, opts.Exporter)
+	}
+	if err != nil {
+		closeOutput()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		closeOutput()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if cerr := closeOutput(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
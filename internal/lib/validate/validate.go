@@ -0,0 +1,120 @@
+// Package validate checks request fields and collects every violation, so a
+// client learns about all bad fields of a request in one round trip.
+package validate
+
+import (
+	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/errs"
+	"github.com/google/uuid"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits bound user supplied values. Zero disables a limit.
+type Limits struct {
+	// MaxTitleLength is counted in characters.
+	MaxTitleLength int
+	// MaxContentBytes applies to note content and to clipped html pages.
+	MaxContentBytes int
+	MaxPageSize     int32
+	// MaxImportNotes bounds the notes sent in one import.
+	MaxImportNotes int
+}
+
+type Violations []errs.FieldViolation
+
+func (v *Violations) Add(field, description string) {
+	*v = append(*v, errs.FieldViolation{Field: field, Description: description})
+}
+
+// Err returns nil when nothing was violated.
+func (v Violations) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return errs.InvalidFields(v...)
+}
+
+// UUID accepts the canonical 36 character form only; an empty value passes
+// unless required.
+func (v *Violations) UUID(field, value string, required bool) {
+	if value == "" {
+		if required {
+			v.Add(field, "is required")
+		}
+		return
+	}
+	if len(value) != 36 {
+		v.Add(field, "must be a UUID")
+		return
+	}
+	if _, err := uuid.Parse(value); err != nil {
+		v.Add(field, "must be a UUID")
+	}
+}
+
+// Title is a single line: no control characters at all.
+func (v *Violations) Title(field, value string, required bool, maxLength int) {
+	if value == "" {
+		if required {
+			v.Add(field, "is required")
+		}
+		return
+	}
+	if !utf8.ValidString(value) {
+		v.Add(field, "must be valid UTF-8")
+		return
+	}
+	if maxLength > 0 && utf8.RuneCountInString(value) > maxLength {
+		v.Add(field, fmt.Sprintf("must be at most %d characters", maxLength))
+	}
+	if hasControl(value, false) {
+		v.Add(field, "must not contain control characters")
+	}
+}
+
+// Text is free form content: tabs and line breaks are allowed.
+func (v *Violations) Text(field, value string, maxBytes int) {
+	if !utf8.ValidString(value) {
+		v.Add(field, "must be valid UTF-8")
+		return
+	}
+	if maxBytes > 0 && len(value) > maxBytes {
+		v.Add(field, fmt.Sprintf("must be at most %d bytes", maxBytes))
+	}
+	if hasControl(value, true) {
+		v.Add(field, "must not contain control characters other than tabs and line breaks")
+	}
+}
+
+// Html checks encoding and size only. Control characters are left to the
+// caller, which strips them before converting the page.
+func (v *Violations) Html(field, value string, maxBytes int) {
+	if !utf8.ValidString(value) {
+		v.Add(field, "must be valid UTF-8")
+		return
+	}
+	if maxBytes > 0 && len(value) > maxBytes {
+		v.Add(field, fmt.Sprintf("must be at most %d bytes", maxBytes))
+	}
+}
+
+func (v *Violations) PageSize(field string, value, max int32) {
+	if value <= 0 {
+		v.Add(field, "must be greater than 0")
+	} else if max > 0 && value > max {
+		v.Add(field, fmt.Sprintf("must be at most %d", max))
+	}
+}
+
+func hasControl(s string, allowWhitespace bool) bool {
+	for _, r := range s {
+		if allowWhitespace && (r == '\t' || r == '\n' || r == '\r') {
+			continue
+		}
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package markdown
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Page is the main content of a clipped web page converted to Markdown.
+type Page struct {
+	Title   string
+	Content string
+}
+
+// boilerplate elements are removed from a clipped page before conversion.
+var boilerplate = map[string]bool{
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true,
+	"button": true, "iframe": true, "svg": true, "canvas": true, "dialog": true,
+	"menu": true, "select": true, "textarea": true, "object": true, "embed": true,
+}
+
+// boilerplateHints are matched against the words of class, id and role
+// attributes, so "comments" and "cookieBanner" match but "commentary" and
+// "shareholders" do not.
+var boilerplateHints = []string{
+	"sidebar", "comment", "advert", "cookie", "banner", "share", "social",
+	"related", "newsletter", "subscribe", "breadcrumb", "navigation", "promo",
+	"popup", "modal",
+}
+
+// Clip extracts the main content of an HTML page and converts it to
+// Markdown. Relative links and images are resolved against baseURL.
+func Clip(src string, baseURL *url.URL) (Page, error) {
+	const op = "markdown.Clip"
+
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return Page{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	page := Page{Title: strings.TrimSpace(collapseSpace(textContent(findFirst(doc, isElement("title")))))}
+
+	main := mainContent(doc)
+	if page.Title == "" {
+		page.Title = strings.TrimSpace(collapseSpace(textContent(findFirst(main, isElement("h1")))))
+	}
+	stripBoilerplate(main)
+	if baseURL != nil {
+		resolveLinks(main, baseURL)
+	}
+
+	c := &converter{seen: make(map[string]bool)}
+	page.Content = c.restore(c.finish(c.node(main)))
+	return page, nil
+}
+
+// mainContent picks the element holding the page content: the only
+// article, then main, then the body.
+func mainContent(doc *html.Node) *html.Node {
+	if articles := findAll(doc, isElement("article")); len(articles) == 1 {
+		return articles[0]
+	}
+	for _, match := range []func(*html.Node) bool{isElement("main"), hasRole("main")} {
+		if n := findFirst(doc, match); n != nil {
+			return n
+		}
+	}
+	if body := findFirst(doc, isElement("body")); body != nil {
+		return body
+	}
+	return doc
+}
+
+func stripBoilerplate(n *html.Node) {
+	for ch := n.FirstChild; ch != nil; {
+		next := ch.NextSibling
+		switch {
+		case ch.Type == html.CommentNode:
+			n.RemoveChild(ch)
+		case ch.Type == html.ElementNode && (dropped[ch.Data] || boilerplate[ch.Data] || looksLikeBoilerplate(ch)):
+			n.RemoveChild(ch)
+		default:
+			stripBoilerplate(ch)
+		}
+		ch = next
+	}
+}
+
+func looksLikeBoilerplate(n *html.Node) bool {
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	for _, word := range words(attr(n, "class") + " " + attr(n, "id") + " " + attr(n, "role")) {
+		for _, h := range boilerplateHints {
+			if word == h || word == h+"s" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// words splits attribute values into lower case words at spaces, dashes,
+// underscores and camelCase humps.
+func words(s string) []string {
+	var out []string
+	var word []rune
+	var prev rune
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || unicode.IsUpper(r) && unicode.IsLower(prev) {
+			if len(word) > 0 {
+				out = append(out, string(word))
+				word = word[:0]
+			}
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, unicode.ToLower(r))
+		}
+		prev = r
+	}
+	if len(word) > 0 {
+		out = append(out, string(word))
+	}
+	return out
+}
+
+func resolveLinks(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		for i, a := range n.Attr {
+			if a.Key != "href" && a.Key != "src" {
+				continue
+			}
+			if ref, err := url.Parse(strings.TrimSpace(a.Val)); err == nil {
+				n.Attr[i].Val = base.ResolveReference(ref).String()
+			}
+		}
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		resolveLinks(ch, base)
+	}
+}
+
+func isElement(name string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == name
+	}
+}
+
+func hasRole(role string) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		return n.Type == html.ElementNode && attr(n, "role") == role
+	}
+}
+
+func findFirst(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n == nil {
+		return nil
+	}
+	if match(n) {
+		return n
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if found := findFirst(ch, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var found []*html.Node
+	if match(n) {
+		found = append(found, n)
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		found = append(found, findAll(ch, match)...)
+	}
+	return found
+}
@@ -0,0 +1,34 @@
+package markdown
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestClipBoilerplateHints(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		kept bool
+	}{
+		{name: "comments class", html: `<div class="comments">gone</div>`},
+		{name: "camel case id", html: `<div id="cookieBanner">gone</div>`},
+		{name: "dashed class", html: `<div class="post social-links">gone</div>`},
+		{name: "word inside a class name", html: `<div class="commentary">kept</div>`, kept: true},
+		{name: "word inside an id", html: `<p id="shareholders">kept</p>`, kept: true},
+		{name: "word inside a dashed class", html: `<div class="unrelated-work">kept</div>`, kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := Clip("<html><body><p>intro</p>"+tt.html+"</body></html>", nil)
+			require.NoError(t, err)
+
+			if tt.kept {
+				assert.Contains(t, page.Content, "kept")
+			} else {
+				assert.NotContains(t, page.Content, "gone")
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package markdown
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FromHTML converts an HTML tree into Markdown. Elements without a Markdown
+// equivalent keep their text content and are returned in unsupported, so the
+// caller can report them instead of silently losing formatting.
+func FromHTML(root *html.Node) (md string, unsupported []string) {
+	c := &converter{seen: make(map[string]bool)}
+	out := c.finish(c.children(root))
+	return c.restore(out), c.unsupported
+}
+
+// dropped elements carry no content worth keeping.
+var dropped = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true,
+	"template": true, "meta": true, "link": true, "title": true,
+}
+
+// transparent elements are replaced by their content.
+var transparent = map[string]bool{
+	"html": true, "body": true, "span": true, "font": true, "u": true, "ins": true,
+	"sup": true, "sub": true, "small": true, "big": true, "abbr": true, "cite": true,
+	"q": true, "mark": true, "label": true, "time": true, "var": true, "kbd": true,
+	"samp": true, "dfn": true, "thead": true, "tbody": true, "tfoot": true,
+	"colgroup": true, "col": true, "caption": true, "figcaption": true,
+}
+
+var blocks = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true,
+	"footer": true, "main": true, "aside": true, "nav": true, "center": true,
+	"figure": true, "address": true, "dl": true, "dt": true, "dd": true,
+}
+
+type converter struct {
+	pre         []string
+	seen        map[string]bool
+	unsupported []string
+}
+
+func (c *converter) report(name string) {
+	if !c.seen[name] {
+		c.seen[name] = true
+		c.unsupported = append(c.unsupported, name)
+	}
+}
+
+func (c *converter) children(n *html.Node) string {
+	var b strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		b.WriteString(c.node(ch))
+	}
+	return b.String()
+}
+
+func (c *converter) node(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escape(collapseSpace(n.Data))
+	case html.ElementNode:
+	case html.DocumentNode:
+		return c.children(n)
+	default:
+		return ""
+	}
+
+	name := n.Data
+	switch {
+	case dropped[name]:
+		return ""
+	case transparent[name]:
+		return c.children(n)
+	case blocks[name]:
+		return "\n\n" + c.children(n) + "\n\n"
+	}
+
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := oneLine(c.children(n))
+		if text == "" {
+			return ""
+		}
+		return "\n\n" + strings.Repeat("#", int(name[1]-'0')) + " " + text + "\n\n"
+	case "br":
+		return "  \n"
+	case "hr":
+		return "\n\n---\n\n"
+	case "strong", "b":
+		return wrap(c.children(n), "**")
+	case "em", "i":
+		return wrap(c.children(n), "_")
+	case "s", "strike", "del":
+		return wrap(c.children(n), "~~")
+	case "code", "tt":
+		text := textContent(n)
+		if text == "" {
+			return ""
+		}
+		// The delimiter has to be longer than any backtick run in the code,
+		// and spaces keep a leading or trailing backtick off the delimiter.
+		longest := longestBacktickRun(text)
+		if longest == 0 {
+			return "`" + text + "`"
+		}
+		delim := strings.Repeat("`", longest+1)
+		return delim + " " + text + " " + delim
+	case "pre":
+		c.pre = append(c.pre, strings.TrimRight(textContent(n), "\n"))
+		return fmt.Sprintf("\n\n\x00%d\x00\n\n", len(c.pre)-1)
+	case "a":
+		text := oneLine(c.children(n))
+		href, ok := safeURL(attr(n, "href"))
+		if !ok {
+			return text
+		}
+		if text == "" {
+			text = escape(href)
+		}
+		return "[" + text + "](" + escapeURL(href) + ")"
+	case "img":
+		src, ok := safeURL(attr(n, "src"))
+		if !ok {
+			return ""
+		}
+		return "![" + escape(attr(n, "alt")) + "](" + escapeURL(src) + ")"
+	case "input":
+		if attr(n, "type") != "checkbox" {
+			c.report(name)
+			return ""
+		}
+		if hasAttr(n, "checked") {
+			return "[x] "
+		}
+		return "[ ] "
+	case "ul", "ol":
+		return "\n\n" + c.list(n) + "\n\n"
+	case "li":
+		// li outside of a list
+		return "\n\n" + c.listItem(n, "- ") + "\n\n"
+	case "blockquote":
+		inner := c.finish(c.children(n))
+		return "\n\n" + prefixLines(inner, "> ") + "\n\n"
+	case "table":
+		return "\n\n" + c.table(n) + "\n\n"
+	}
+
+	c.report(name)
+	if isBlock(n) {
+		return "\n\n" + c.children(n) + "\n\n"
+	}
+	return c.children(n)
+}
+
+func (c *converter) list(n *html.Node) string {
+	var items []string
+	num := 1
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type != html.ElementNode {
+			continue
+		}
+		if ch.Data != "li" {
+			// nested list placed directly inside the list
+			if ch.Data == "ul" || ch.Data == "ol" {
+				items = append(items, indentMark+indentMark+indent(c.list(ch), indentMark+indentMark))
+			}
+			continue
+		}
+		marker := "- "
+		if n.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", num)
+			num++
+		}
+		items = append(items, c.listItem(ch, marker))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *converter) listItem(n *html.Node, marker string) string {
+	inner := c.finish(c.children(n))
+	inner = multiBlank.ReplaceAllString(inner, "\n")
+	return marker + indent(inner, strings.Repeat(indentMark, len(marker)))
+}
+
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.Type != html.ElementNode {
+				continue
+			}
+			if ch.Data != "tr" {
+				walk(ch)
+				continue
+			}
+			var row []string
+			for cell := ch.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+					text := oneLine(c.finish(c.children(cell)))
+					row = append(row, strings.ReplaceAll(text, "|", `\|`))
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString(strings.Repeat("| --- ", width) + "|\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// indentMark stands for a space of list indentation until the output is
+// complete, so that whitespace normalization of enclosing blocks keeps it.
+const indentMark = "\x01"
+
+var (
+	multiBlank  = regexp.MustCompile(`\n{2,}`)
+	manyNewline = regexp.MustCompile(`\n{3,}`)
+	preToken    = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// finish normalizes whitespace around blocks.
+func (c *converter) finish(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasSuffix(trimmed, "  ") {
+			lines[i] = strings.TrimRight(trimmed, " ") + "  "
+		} else {
+			lines[i] = strings.TrimRight(trimmed, " ")
+		}
+	}
+	s = strings.Join(lines, "\n")
+	s = manyNewline.ReplaceAllString(s, "\n\n")
+	s = strings.Trim(s, "\n")
+	return strings.TrimSuffix(s, "  ")
+}
+
+// restore puts preformatted text back in place, carrying the indentation of
+// its line over to every line of the code block, and turns indentation marks
+// into spaces.
+func (c *converter) restore(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		loc := preToken.FindStringSubmatchIndex(line)
+		if loc == nil {
+			out = append(out, line)
+			continue
+		}
+		prefix := line[:loc[0]]
+		i, _ := strconv.Atoi(line[loc[2]:loc[3]])
+		fence := codeFence(c.pre[i])
+		out = append(out, prefix+fence)
+		// a list marker only belongs on the first line
+		cont := strings.Map(func(r rune) rune {
+			if r == '>' {
+				return r
+			}
+			return ' '
+		}, prefix)
+		for _, code := range strings.Split(c.pre[i], "\n") {
+			out = append(out, strings.TrimRight(cont+code, " "))
+		}
+		out = append(out, cont+fence)
+	}
+	return strings.ReplaceAll(strings.Join(out, "\n"), indentMark, " ")
+}
+
+// codeFence returns a backtick fence longer than any backtick run in code, so
+// no line of the code can close the block early.
+func codeFence(code string) string {
+	return strings.Repeat("`", max(3, longestBacktickRun(code)+1))
+}
+
+func longestBacktickRun(s string) int {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+func wrap(s, marker string) string {
+	inner := strings.TrimSpace(s)
+	if inner == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " \n"))]
+	trail := s[len(strings.TrimRight(s, " \n")):]
+	return lead + marker + inner + marker + trail
+}
+
+func oneLine(s string) string {
+	return strings.TrimSpace(collapseSpace(strings.ReplaceAll(s, "  \n", " ")))
+}
+
+var spaces = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+func collapseSpace(s string) string {
+	return spaces.ReplaceAllString(s, " ")
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+var urlEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29")
+
+// safeSchemes are the link schemes kept in Markdown; relative links have none.
+var safeSchemes = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+
+// safeURL trims raw, as browsers do, and reports whether it is a relative
+// link or uses one of safeSchemes, which rules out javascript: and data: urls.
+func safeURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !safeSchemes[u.Scheme] {
+		return "", false
+	}
+	return raw, true
+}
+
+func escapeURL(s string) string {
+	return urlEscaper.Replace(strings.TrimSpace(s))
+}
+
+func indent(s, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func textContent(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && n.Data == "br" {
+		return "\n"
+	}
+	var b strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		b.WriteString(textContent(ch))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlock(n *html.Node) bool {
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode && (blocks[ch.Data] || ch.Data == "p" || ch.Data == "table" || ch.Data == "ul" || ch.Data == "ol") {
+			return true
+		}
+	}
+	return false
+}
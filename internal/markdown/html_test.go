@@ -0,0 +1,93 @@
+package markdown
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+	"strings"
+	"testing"
+)
+
+func TestFromHTMLCodeFence(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		fence string
+	}{
+		{name: "plain code", html: "<pre>x := 1</pre>", fence: "```"},
+		{name: "fence inside code", html: "<pre>```\nnot markdown\n```</pre>", fence: "````"},
+		{name: "long backtick run", html: "<pre>a `````` b</pre>", fence: "```````"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := html.Parse(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			md, _ := FromHTML(root)
+
+			code := textContent(find(root, "pre"))
+			assert.Equal(t, tt.fence+"\n"+code+"\n"+tt.fence, strings.TrimSpace(md))
+		})
+	}
+}
+
+func TestFromHTMLInlineCode(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "plain code", html: "<p><code>x := 1</code></p>", want: "`x := 1`"},
+		{name: "single backtick", html: "<p><code>a ` b</code></p>", want: "`` a ` b ``"},
+		{name: "backtick run", html: "<p><code>a `` b</code></p>", want: "``` a `` b ```"},
+		{name: "tt element", html: "<p><tt>```</tt></p>", want: "```` ``` ````"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := html.Parse(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			md, _ := FromHTML(root)
+
+			assert.Equal(t, tt.want, strings.TrimSpace(md))
+		})
+	}
+}
+
+func TestFromHTMLLinkSchemes(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "https link", html: `<a href="https://example.com/a b">x</a>`, want: "[x](https://example.com/a%20b)"},
+		{name: "relative link", html: `<a href="/docs">x</a>`, want: "[x](/docs)"},
+		{name: "mailto link", html: `<a href="mailto:a@example.com">x</a>`, want: "[x](mailto:a@example.com)"},
+		{name: "javascript link", html: `<a href="javascript:alert(1)">x</a>`, want: "x"},
+		{name: "padded javascript link", html: `<a href=" JavaScript:alert(1)">x</a>`, want: "x"},
+		{name: "data link", html: `<a href="data:text/html,<script>alert(1)</script>">x</a>`, want: "x"},
+		{name: "javascript image", html: `<img src=" javascript:alert(1)" alt="x">`, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := html.Parse(strings.NewReader(tt.html))
+			require.NoError(t, err)
+
+			md, _ := FromHTML(root)
+
+			assert.Equal(t, tt.want, strings.TrimSpace(md))
+		})
+	}
+}
+
+func find(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package markdown
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// Renderer turns note content into sanitized HTML. Results are cached by
+// content hash, so unchanged notes are rendered once.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	rendered models.RenderedContent
+}
+
+// NewRenderer creates a Renderer keeping at most cacheSize rendered results.
+// A cacheSize of zero disables caching.
+func NewRenderer(cacheSize int) *Renderer {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("type", "checked", "disabled").OnElements("input")
+	policy.RequireNoReferrerOnLinks(true)
+
+	return &Renderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		),
+		policy:   policy,
+		capacity: cacheSize,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (r *Renderer) Render(format models.ContentFormat, content string) (models.RenderedContent, error) {
+	const op = "markdown.Render"
+
+	key := string(format) + ":" + models.ContentHash(content)
+	if rendered, ok := r.get(key); ok {
+		return rendered, nil
+	}
+
+	var rendered models.RenderedContent
+	switch format {
+	case models.ContentFormatMarkdown:
+		source := []byte(content)
+		doc := r.md.Parser().Parse(text.NewReader(source))
+		var buf bytes.Buffer
+		if err := r.md.Renderer().Render(&buf, source, doc); err != nil {
+			return models.RenderedContent{}, fmt.Errorf("%s: %w", op, err)
+		}
+		rendered.HTML = r.policy.Sanitize(buf.String())
+		rendered.Outline = outline(doc, source)
+	default:
+		rendered.HTML = renderPlain(content)
+	}
+
+	r.put(key, rendered)
+	return rendered, nil
+}
+
+func (r *Renderer) get(key string) (models.RenderedContent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	el, ok := r.entries[key]
+	if !ok {
+		return models.RenderedContent{}, false
+	}
+	r.lru.MoveToFront(el)
+	return el.Value.(*cacheEntry).rendered, true
+}
+
+func (r *Renderer) put(key string, rendered models.RenderedContent) {
+	if r.capacity <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if el, ok := r.entries[key]; ok {
+		r.lru.MoveToFront(el)
+		return
+	}
+	r.entries[key] = r.lru.PushFront(&cacheEntry{key: key, rendered: rendered})
+	if r.lru.Len() > r.capacity {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func outline(doc ast.Node, source []byte) []models.Heading {
+	var headings []models.Heading
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		heading := models.Heading{
+			Level: h.Level,
+			Text:  string(nodeText(h, source)),
+		}
+		if id, ok := h.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				heading.Anchor = string(b)
+			}
+		}
+		headings = append(headings, heading)
+		return ast.WalkSkipChildren, nil
+	})
+	return headings
+}
+
+func nodeText(n ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	for ch := n.FirstChild(); ch != nil; ch = ch.NextSibling() {
+		switch t := ch.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(t.Value)
+		default:
+			buf.Write(nodeText(ch, source))
+		}
+	}
+	return buf.Bytes()
+}
+
+// renderPlain escapes plain text, keeping paragraphs and line breaks.
+func renderPlain(content string) string {
+	var b strings.Builder
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	for _, para := range strings.Split(content, "\n\n") {
+		para = strings.Trim(para, "\n")
+		if para == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+// Package metrics holds the Prometheus collectors of the service and serves
+// them over HTTP.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const namespace = "notes"
+
+// Metrics implements the observers the service and storage layers accept.
+type Metrics struct {
+	registry      *prometheus.Registry
+	notesCreated  *prometheus.CounterVec
+	notesDeleted  prometheus.Counter
+	queryDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		notesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "created_total",
+			Help:      "Notes created, by origin: create, clip or import.",
+		}, []string{"origin"}),
+		notesDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "deleted_total",
+			Help:      "Notes deleted.",
+		}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Duration of storage operations, retries included.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"query", "result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.notesCreated,
+		m.notesDeleted,
+		m.queryDuration,
+	)
+	return m
+}
+
+// Registerer lets other packages add their collectors.
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registry
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) NotesCreated(origin string, count int) {
+	if count > 0 {
+		m.notesCreated.WithLabelValues(origin).Add(float64(count))
+	}
+}
+
+func (m *Metrics) NotesDeleted(count int) {
+	if count > 0 {
+		m.notesDeleted.Add(float64(count))
+	}
+}
+
+// ObserveQuery takes ops named like "storage.postgres.GetNoteById" and
+// labels them with the method name only.
+func (m *Metrics) ObserveQuery(op string, duration time.Duration, err error) {
+	query := op[strings.LastIndex(op, ".")+1:]
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.queryDuration.WithLabelValues(query, result).Observe(duration.Seconds())
+}
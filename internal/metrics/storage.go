@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"strings"
+)
+
+type StorageStats interface {
+	PoolStat() *pgxpool.Stat
+	RetryCounts() map[string]int64
+}
+
+// storageCollector reads pool and retry statistics on every scrape.
+type storageCollector struct {
+	stats StorageStats
+
+	maxConns        *prometheus.Desc
+	totalConns      *prometheus.Desc
+	idleConns       *prometheus.Desc
+	acquiredConns   *prometheus.Desc
+	acquires        *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+	acquireSeconds  *prometheus.Desc
+	retries         *prometheus.Desc
+}
+
+// RegisterStorage exports the primary pool gauges and the retry counters.
+func (m *Metrics) RegisterStorage(stats StorageStats) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, labels, nil)
+	}
+	m.registry.MustRegister(&storageCollector{
+		stats:           stats,
+		maxConns:        desc("pool_max_conns", "Maximum size of the connection pool."),
+		totalConns:      desc("pool_total_conns", "Open connections, idle or in use."),
+		idleConns:       desc("pool_idle_conns", "Idle connections."),
+		acquiredConns:   desc("pool_acquired_conns", "Connections in use."),
+		acquires:        desc("pool_acquires_total", "Successful connection acquires."),
+		emptyAcquires:   desc("pool_empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquire: desc("pool_canceled_acquires_total", "Acquires canceled by their context."),
+		acquireSeconds:  desc("pool_acquire_seconds_total", "Time spent waiting for connections."),
+		retries:         desc("retries_total", "Operations retried after a transient error.", "query"),
+	})
+}
+
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.maxConns, c.totalConns, c.idleConns, c.acquiredConns,
+		c.acquires, c.emptyAcquires, c.canceledAcquire, c.acquireSeconds, c.retries,
+	} {
+		ch <- d
+	}
+}
+
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats.PoolStat()
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireSeconds, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	for op, n := range c.stats.RetryCounts() {
+		query := op[strings.LastIndex(op, ".")+1:]
+		ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(n), query)
+	}
+}
@@ -0,0 +1,128 @@
+// Package migrator applies the schema migrations, either the ones embedded
+// in the binary or, for development, the ones in a directory.
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/crewblade/notes_service/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+var (
+	ErrDirty        = errors.New("schema is dirty; fix it and run the migrator's force command")
+	ErrSchemaTooNew = errors.New("schema is newer than this binary's migrations")
+	errNoMigrations = errors.New("no migrations found")
+)
+
+const defaultLockTimeout = 15 * time.Second
+
+// Source opens migrationsPath, or the embedded migrations when it is empty.
+func Source(migrationsPath string) (source.Driver, string, error) {
+	if migrationsPath == "" {
+		src, err := iofs.New(migrations.FS, ".")
+		return src, "iofs", err
+	}
+	src, err := source.Open("file://" + migrationsPath)
+	return src, "file", err
+}
+
+// New connects to the database. A zero lockTimeout keeps the default.
+func New(connectionString, migrationsPath string, lockTimeout time.Duration) (*migrate.Migrate, error) {
+	const op = "migrator.New"
+	src, name, err := Source(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	db, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		src.Close()
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	m, err := migrate.NewWithInstance(name, src, "postgres", driver)
+	if err != nil {
+		src.Close()
+		driver.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Every command that changes the schema takes the driver's advisory lock,
+	// so a concurrent migrator waits up to LockTimeout and then fails.
+	m.LockTimeout = defaultLockTimeout
+	if lockTimeout > 0 {
+		m.LockTimeout = lockTimeout
+	}
+	return m, nil
+}
+
+// Versions lists the versions available in the source, in order.
+func Versions(migrationsPath string) ([]uint, error) {
+	src, _, err := Source(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	var versions []uint
+	v, err := src.First()
+	for err == nil {
+		versions = append(versions, v)
+		v, err = src.Next(v)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Up applies pending migrations before the service starts. It refuses to
+// touch a dirty schema or one migrated past the newest version it knows, as
+// the running code would not match it.
+func Up(log *slog.Logger, connectionString, migrationsPath string) error {
+	const op = "migrator.Up"
+	log = log.With(slog.String("op", op))
+
+	versions, err := Versions(migrationsPath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("%s: %w", op, errNoMigrations)
+	}
+	latest := versions[len(versions)-1]
+
+	m, err := New(connectionString, migrationsPath, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer m.Close()
+
+	current, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	switch {
+	case dirty:
+		return fmt.Errorf("%s: version %d: %w", op, current, ErrDirty)
+	case current > latest:
+		return fmt.Errorf("%s: version %d > %d: %w", op, current, latest, ErrSchemaTooNew)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("schema is up to date", slog.Uint64("from", uint64(current)), slog.Uint64("version", uint64(latest)))
+	return nil
+}
@@ -0,0 +1,345 @@
+package attachments
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/errs"
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"github.com/crewblade/notes_service/internal/lib/validate"
+	"github.com/crewblade/notes_service/internal/storage"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTooLarge        = errs.New(errs.QuotaExceeded, "ATTACHMENT_TOO_LARGE", "attachment is too large")
+	ErrUnsupportedType = errs.New(errs.InvalidArgument, "ATTACHMENT_TYPE_NOT_ALLOWED", "attachment type is not allowed")
+)
+
+type Attachments struct {
+	log                *slog.Logger
+	attachmentSaver    AttachmentSaver
+	attachmentProvider AttachmentProvider
+	attachmentDeleter  AttachmentDeleter
+	blobReferences     BlobReferences
+	blobStore          BlobStore
+	maxSize            int64
+	allowedTypes       []string
+	ids                IDGenerator
+	clock              Clock
+	// blobLocks serialize garbage collection of a blob with uploads of the
+	// same content, so a blob is never deleted while a reference to it is
+	// being saved.
+	blobLocks [64]sync.Mutex
+}
+
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name AttachmentSaver
+type AttachmentSaver interface {
+	SaveAttachment(ctx context.Context, attachment models.Attachment) (models.Attachment, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name AttachmentProvider
+type AttachmentProvider interface {
+	GetAttachment(ctx context.Context, id string) (models.Attachment, error)
+	ListAttachments(ctx context.Context, noteId string) ([]models.Attachment, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name AttachmentDeleter
+type AttachmentDeleter interface {
+	DeleteAttachment(ctx context.Context, id string) (models.Attachment, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name BlobReferences
+type BlobReferences interface {
+	BlobReferenced(ctx context.Context, hash string) (bool, error)
+}
+
+// BlobStore keeps attachment content addressed by its SHA-256 hash.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name BlobStore
+type BlobStore interface {
+	Put(ctx context.Context, r io.Reader) (hash string, size int64, err error)
+	Open(ctx context.Context, hash string) (io.ReadCloser, error)
+	Delete(ctx context.Context, hash string) error
+	Blobs(ctx context.Context) ([]models.Blob, error)
+	// Stat returns storage.BlobNotFound for a missing blob.
+	Stat(ctx context.Context, hash string) (models.Blob, error)
+}
+
+// sniffLen is the amount of content http.DetectContentType looks at.
+const sniffLen = 512
+
+// maxFileNameLength matches the usual file system limit on name length.
+const maxFileNameLength = 255
+
+// New creates the attachments service. allowedTypes holds MIME types or
+// prefixes ending with "/" (e.g. "image/") accepted on upload.
+func New(
+	log *slog.Logger,
+	attachmentSaver AttachmentSaver,
+	attachmentProvider AttachmentProvider,
+	attachmentDeleter AttachmentDeleter,
+	blobReferences BlobReferences,
+	blobStore BlobStore,
+	maxSize int64,
+	allowedTypes []string,
+	ids IDGenerator,
+	clock Clock,
+) *Attachments {
+	return &Attachments{
+		log:                log,
+		attachmentSaver:    attachmentSaver,
+		attachmentProvider: attachmentProvider,
+		attachmentDeleter:  attachmentDeleter,
+		blobReferences:     blobReferences,
+		blobStore:          blobStore,
+		maxSize:            maxSize,
+		allowedTypes:       allowedTypes,
+		ids:                ids,
+		clock:              clock,
+	}
+}
+
+// UploadAttachment stores content read from r and attaches it to a note.
+// The MIME type is sniffed from the content; the declared one is only used
+// to refine a generic text type.
+func (a *Attachments) UploadAttachment(ctx context.Context, noteId, fileName, declaredType string, r io.Reader) (models.Attachment, error) {
+	const op = "services.attachments.UploadAttachment"
+	log := logging.FromContext(ctx, a.log).With(slog.String("op", op), slog.String("note_id", noteId))
+	var v validate.Violations
+	v.UUID("note_id", noteId, true)
+	v.Title("file_name", fileName, true, maxFileNameLength)
+	if err := v.Err(); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	mimeType := detectType(head, declaredType)
+	if !a.allowed(mimeType) {
+		log.Warn("rejected attachment type", slog.String("mime_type", mimeType))
+		return models.Attachment{}, fmt.Errorf("%s: %s: %w", op, mimeType, ErrUnsupportedType)
+	}
+
+	hash, size, err := a.blobStore.Put(ctx, &limitReader{r: br, remaining: a.maxSize})
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			log.Warn("rejected attachment size", slog.Int64("max_size", a.maxSize))
+		}
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	unlock := a.lockBlob(hash)
+	defer unlock()
+	// Put refreshed the blob, but garbage collection may have deleted it
+	// right before that if it had already checked the time.
+	if _, err := a.blobStore.Stat(ctx, hash); err != nil {
+		if errors.Is(err, storage.BlobNotFound) {
+			log.Warn("blob removed during upload", slog.String("sha256", hash))
+			return models.Attachment{}, fmt.Errorf("%s: %w", op, errs.Transient(err, 0))
+		}
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	id, err := a.ids.NewID()
+	if err != nil {
+		log.Error("failed to generate id", slog.String("err", err.Error()))
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	attachment, err := a.attachmentSaver.SaveAttachment(ctx, models.Attachment{
+		Id:       id,
+		NoteId:   noteId,
+		FileName: fileName,
+		MimeType: mimeType,
+		Size:     size,
+		Sha256:   hash,
+	})
+	if err != nil {
+		if errors.Is(err, storage.IdNotFound) {
+			log.Warn("Id not found", slog.String("err", err.Error()))
+		}
+		// the blob, if new, is left to garbage collection
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("Attachment uploaded", slog.String("id", attachment.Id), slog.Int64("size", size))
+	return attachment, nil
+}
+
+// DownloadAttachment returns attachment metadata and its content.
+// The caller must close the returned reader.
+func (a *Attachments) DownloadAttachment(ctx context.Context, id string) (models.Attachment, io.ReadCloser, error) {
+	const op = "services.attachments.DownloadAttachment"
+	log := logging.FromContext(ctx, a.log).With(slog.String("op", op))
+	if err := validateId("id", id); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	attachment, err := a.attachmentProvider.GetAttachment(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.IdNotFound) {
+			log.Warn("Id not found", slog.String("err", err.Error()))
+		}
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	content, err := a.blobStore.Open(ctx, attachment.Sha256)
+	if err != nil {
+		log.Error("failed to open blob", slog.String("sha256", attachment.Sha256), slog.String("err", err.Error()))
+		return models.Attachment{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return attachment, content, nil
+}
+
+func (a *Attachments) ListAttachments(ctx context.Context, noteId string) ([]models.Attachment, error) {
+	const op = "services.attachments.ListAttachments"
+	if err := validateId("note_id", noteId); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	attachments, err := a.attachmentProvider.ListAttachments(ctx, noteId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment removes attachment metadata. The blob stays until
+// garbage collection finds it unreferenced.
+func (a *Attachments) DeleteAttachment(ctx context.Context, id string) (models.Attachment, error) {
+	const op = "services.attachments.DeleteAttachment"
+	log := logging.FromContext(ctx, a.log).With(slog.String("op", op))
+	if err := validateId("id", id); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	attachment, err := a.attachmentDeleter.DeleteAttachment(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.IdNotFound) {
+			log.Warn("Id not found", slog.String("err", err.Error()))
+		}
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("Attachment deleted", slog.String("id", attachment.Id))
+	return attachment, nil
+}
+
+// CollectGarbage deletes blobs no attachment refers to. Blobs modified within
+// gracePeriod are kept: their upload may not have saved its metadata yet.
+func (a *Attachments) CollectGarbage(ctx context.Context, gracePeriod time.Duration) (removed int, err error) {
+	const op = "services.attachments.CollectGarbage"
+	log := logging.FromContext(ctx, a.log).With(slog.String("op", op))
+
+	blobs, err := a.blobStore.Blobs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	cutoff := a.clock.Now().Add(-gracePeriod)
+	for _, blob := range blobs {
+		if blob.ModTime.After(cutoff) {
+			continue
+		}
+		deleted, err := a.collectBlob(ctx, blob.Hash, cutoff)
+		if err != nil {
+			return removed, fmt.Errorf("%s: %w", op, err)
+		}
+		if deleted {
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Info("Orphaned blobs removed", slog.Int("count", removed))
+	}
+	return removed, nil
+}
+
+// collectBlob deletes the blob unless it was refreshed since cutoff or is
+// referenced. Both are checked under the blob lock, after the listing, since
+// an upload of the same content may have come in meanwhile.
+func (a *Attachments) collectBlob(ctx context.Context, hash string, cutoff time.Time) (bool, error) {
+	unlock := a.lockBlob(hash)
+	defer unlock()
+	blob, err := a.blobStore.Stat(ctx, hash)
+	if err != nil {
+		if errors.Is(err, storage.BlobNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if blob.ModTime.After(cutoff) {
+		return false, nil
+	}
+	referenced, err := a.blobReferences.BlobReferenced(ctx, hash)
+	if err != nil || referenced {
+		return false, err
+	}
+	if err := a.blobStore.Delete(ctx, hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (a *Attachments) lockBlob(hash string) (unlock func()) {
+	h := fnv.New32a()
+	h.Write([]byte(hash))
+	mu := &a.blobLocks[h.Sum32()%uint32(len(a.blobLocks))]
+	mu.Lock()
+	return mu.Unlock
+}
+
+func validateId(field, id string) error {
+	var v validate.Violations
+	v.UUID(field, id, true)
+	return v.Err()
+}
+
+func (a *Attachments) allowed(mimeType string) bool {
+	for _, t := range a.allowedTypes {
+		if t == mimeType || (strings.HasSuffix(t, "/") && strings.HasPrefix(mimeType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func detectType(head []byte, declared string) string {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	declared, _, _ = mime.ParseMediaType(declared)
+	// Sniffing can't tell text formats apart, so a declared text type wins.
+	if sniffed == "text/plain" && strings.HasPrefix(declared, "text/") {
+		return declared
+	}
+	return sniffed
+}
+
+// limitReader fails with ErrTooLarge once more than remaining bytes are read.
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
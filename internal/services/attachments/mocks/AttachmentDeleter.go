@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/crewblade/notes_service/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AttachmentDeleter is an autogenerated mock type for the AttachmentDeleter type
+type AttachmentDeleter struct {
+	mock.Mock
+}
+
+// DeleteAttachment provides a mock function with given fields: ctx, id
+func (_m *AttachmentDeleter) DeleteAttachment(ctx context.Context, id string) (models.Attachment, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAttachment")
+	}
+
+	var r0 models.Attachment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Attachment, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Attachment); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(models.Attachment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAttachmentDeleter creates a new instance of AttachmentDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAttachmentDeleter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AttachmentDeleter {
+	mock := &AttachmentDeleter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,87 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/crewblade/notes_service/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AttachmentProvider is an autogenerated mock type for the AttachmentProvider type
+type AttachmentProvider struct {
+	mock.Mock
+}
+
+// GetAttachment provides a mock function with given fields: ctx, id
+func (_m *AttachmentProvider) GetAttachment(ctx context.Context, id string) (models.Attachment, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAttachment")
+	}
+
+	var r0 models.Attachment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Attachment, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Attachment); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(models.Attachment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAttachments provides a mock function with given fields: ctx, noteId
+func (_m *AttachmentProvider) ListAttachments(ctx context.Context, noteId string) ([]models.Attachment, error) {
+	ret := _m.Called(ctx, noteId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAttachments")
+	}
+
+	var r0 []models.Attachment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.Attachment, error)); ok {
+		return rf(ctx, noteId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.Attachment); ok {
+		r0 = rf(ctx, noteId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Attachment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, noteId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAttachmentProvider creates a new instance of AttachmentProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAttachmentProvider(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AttachmentProvider {
+	mock := &AttachmentProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
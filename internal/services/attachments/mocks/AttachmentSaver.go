@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/crewblade/notes_service/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AttachmentSaver is an autogenerated mock type for the AttachmentSaver type
+type AttachmentSaver struct {
+	mock.Mock
+}
+
+// SaveAttachment provides a mock function with given fields: ctx, attachment
+func (_m *AttachmentSaver) SaveAttachment(ctx context.Context, attachment models.Attachment) (models.Attachment, error) {
+	ret := _m.Called(ctx, attachment)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveAttachment")
+	}
+
+	var r0 models.Attachment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Attachment) (models.Attachment, error)); ok {
+		return rf(ctx, attachment)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Attachment) models.Attachment); ok {
+		r0 = rf(ctx, attachment)
+	} else {
+		r0 = ret.Get(0).(models.Attachment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Attachment) error); ok {
+		r1 = rf(ctx, attachment)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAttachmentSaver creates a new instance of AttachmentSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAttachmentSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AttachmentSaver {
+	mock := &AttachmentSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
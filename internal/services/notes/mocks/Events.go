@@ -0,0 +1,34 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import mock "github.com/stretchr/testify/mock"
+
+// Events is an autogenerated mock type for the Events type
+type Events struct {
+	mock.Mock
+}
+
+// NotesCreated provides a mock function with given fields: origin, count
+func (_m *Events) NotesCreated(origin string, count int) {
+	_m.Called(origin, count)
+}
+
+// NotesDeleted provides a mock function with given fields: count
+func (_m *Events) NotesDeleted(count int) {
+	_m.Called(count)
+}
+
+// NewEvents creates a new instance of Events. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewEvents(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Events {
+	mock := &Events{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
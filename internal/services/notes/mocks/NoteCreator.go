@@ -1,10 +1,11 @@
-// Code generated by mockery v2.42.1. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package mocks
 
 import (
 	context "context"
 
+	models "github.com/crewblade/notes_service/internal/domain/models"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,9 +14,9 @@ type NoteCreator struct {
 	mock.Mock
 }
 
-// CreateNote provides a mock function with given fields: ctx, title, content
-func (_m *NoteCreator) CreateNote(ctx context.Context, title string, content string) (string, error) {
-	ret := _m.Called(ctx, title, content)
+// CreateNote provides a mock function with given fields: ctx, note
+func (_m *NoteCreator) CreateNote(ctx context.Context, note models.Note) (string, error) {
+	ret := _m.Called(ctx, note)
 
 	if len(ret) == 0 {
 		panic("no return value specified for CreateNote")
@@ -23,17 +24,17 @@ func (_m *NoteCreator) CreateNote(ctx context.Context, title string, content str
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
-		return rf(ctx, title, content)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Note) (string, error)); ok {
+		return rf(ctx, note)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
-		r0 = rf(ctx, title, content)
+	if rf, ok := ret.Get(0).(func(context.Context, models.Note) string); ok {
+		r0 = rf(ctx, note)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, title, content)
+	if rf, ok := ret.Get(1).(func(context.Context, models.Note) error); ok {
+		r1 = rf(ctx, note)
 	} else {
 		r1 = ret.Error(1)
 	}
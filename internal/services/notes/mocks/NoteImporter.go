@@ -0,0 +1,59 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/crewblade/notes_service/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// NoteImporter is an autogenerated mock type for the NoteImporter type
+type NoteImporter struct {
+	mock.Mock
+}
+
+// ImportNotes provides a mock function with given fields: ctx, _a1, dryRun
+func (_m *NoteImporter) ImportNotes(ctx context.Context, _a1 []models.ImportNote, dryRun bool) ([]models.ImportResult, error) {
+	ret := _m.Called(ctx, _a1, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportNotes")
+	}
+
+	var r0 []models.ImportResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.ImportNote, bool) ([]models.ImportResult, error)); ok {
+		return rf(ctx, _a1, dryRun)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.ImportNote, bool) []models.ImportResult); ok {
+		r0 = rf(ctx, _a1, dryRun)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.ImportResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.ImportNote, bool) error); ok {
+		r1 = rf(ctx, _a1, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewNoteImporter creates a new instance of NoteImporter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewNoteImporter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *NoteImporter {
+	mock := &NoteImporter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,55 @@
+// Code generated by mockery v2.53.7. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "github.com/crewblade/notes_service/internal/domain/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// NoteRenderer is an autogenerated mock type for the NoteRenderer type
+type NoteRenderer struct {
+	mock.Mock
+}
+
+// Render provides a mock function with given fields: format, content
+func (_m *NoteRenderer) Render(format models.ContentFormat, content string) (models.RenderedContent, error) {
+	ret := _m.Called(format, content)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Render")
+	}
+
+	var r0 models.RenderedContent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(models.ContentFormat, string) (models.RenderedContent, error)); ok {
+		return rf(format, content)
+	}
+	if rf, ok := ret.Get(0).(func(models.ContentFormat, string) models.RenderedContent); ok {
+		r0 = rf(format, content)
+	} else {
+		r0 = ret.Get(0).(models.RenderedContent)
+	}
+
+	if rf, ok := ret.Get(1).(func(models.ContentFormat, string) error); ok {
+		r1 = rf(format, content)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewNoteRenderer creates a new instance of NoteRenderer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewNoteRenderer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *NoteRenderer {
+	mock := &NoteRenderer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -1,4 +1,4 @@
-// Code generated by mockery v2.42.1. DO NOT EDIT.
+// Code generated by mockery v2.53.7. DO NOT EDIT.
 
 package mocks
 
@@ -14,9 +14,9 @@ type NoteUpdater struct {
 	mock.Mock
 }
 
-// UpdateNote provides a mock function with given fields: ctx, id, title, content
-func (_m *NoteUpdater) UpdateNote(ctx context.Context, id string, title string, content string) (models.Note, error) {
-	ret := _m.Called(ctx, id, title, content)
+// UpdateNote provides a mock function with given fields: ctx, id, title, content, format
+func (_m *NoteUpdater) UpdateNote(ctx context.Context, id string, title string, content string, format models.ContentFormat) (models.Note, error) {
+	ret := _m.Called(ctx, id, title, content, format)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateNote")
@@ -24,17 +24,17 @@ func (_m *NoteUpdater) UpdateNote(ctx context.Context, id string, title string,
 
 	var r0 models.Note
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (models.Note, error)); ok {
-		return rf(ctx, id, title, content)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, models.ContentFormat) (models.Note, error)); ok {
+		return rf(ctx, id, title, content, format)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) models.Note); ok {
-		r0 = rf(ctx, id, title, content)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, models.ContentFormat) models.Note); ok {
+		r0 = rf(ctx, id, title, content, format)
 	} else {
 		r0 = ret.Get(0).(models.Note)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
-		r1 = rf(ctx, id, title, content)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, models.ContentFormat) error); ok {
+		r1 = rf(ctx, id, title, content, format)
 	} else {
 		r1 = ret.Error(1)
 	}
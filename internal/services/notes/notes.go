@@ -4,9 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/errs"
 	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"github.com/crewblade/notes_service/internal/lib/validate"
+	"github.com/crewblade/notes_service/internal/markdown"
 	"github.com/crewblade/notes_service/internal/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
 	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Notes struct {
@@ -16,11 +29,40 @@ type Notes struct {
 	noteUpdater    NoteUpdater
 	noteDeleter    NoteDeleter
 	noteLister     NoteLister
+	noteImporter   NoteImporter
+	noteRenderer   NoteRenderer
+	ids            IDGenerator
+	clock          Clock
+	limits         validate.Limits
+	events         Events
+}
+
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+// Origins of created notes, as reported to Events.
+const (
+	OriginCreate = "create"
+	OriginClip   = "clip"
+	OriginImport = "import"
+)
+
+// Events receives business events, for metrics.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name Events
+type Events interface {
+	NotesCreated(origin string, count int)
+	NotesDeleted(count int)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name NoteCreator
 type NoteCreator interface {
-	CreateNote(ctx context.Context, title string, content string) (id string, err error)
+	CreateNote(ctx context.Context, note models.Note) (id string, err error)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name NoteGetterById
@@ -30,7 +72,7 @@ type NoteGetterById interface {
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name NoteUpdater
 type NoteUpdater interface {
-	UpdateNote(ctx context.Context, id, title, content string) (models.Note, error)
+	UpdateNote(ctx context.Context, id, title, content string, format models.ContentFormat) (models.Note, error)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name NoteDeleter
@@ -43,6 +85,23 @@ type NoteLister interface {
 	GetNotes(ctx context.Context, limit int32, offset_id string) (notes []models.Note, next_offset_id string, err error)
 }
 
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name NoteImporter
+type NoteImporter interface {
+	ImportNotes(ctx context.Context, notes []models.ImportNote, dryRun bool) ([]models.ImportResult, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name NoteRenderer
+type NoteRenderer interface {
+	Render(format models.ContentFormat, content string) (models.RenderedContent, error)
+}
+
+var tracer = otel.Tracer("github.com/crewblade/notes_service/internal/services/notes")
+
+// importBatchSize is the number of notes written in one transaction.
+// Batches committed before a failure stay in place, so an import can be
+// resumed by running it again: already imported notes are reported as duplicates.
+const importBatchSize = 100
+
 func New(
 	log *slog.Logger,
 	noteCreator NoteCreator,
@@ -50,6 +109,12 @@ func New(
 	noteUpdater NoteUpdater,
 	noteDeleter NoteDeleter,
 	noteLister NoteLister,
+	noteImporter NoteImporter,
+	noteRenderer NoteRenderer,
+	ids IDGenerator,
+	clock Clock,
+	limits validate.Limits,
+	events Events,
 ) *Notes {
 	return &Notes{
 		log:            log,
@@ -58,24 +123,124 @@ func New(
 		noteUpdater:    noteUpdater,
 		noteDeleter:    noteDeleter,
 		noteLister:     noteLister,
+		noteImporter:   noteImporter,
+		noteRenderer:   noteRenderer,
+		ids:            ids,
+		clock:          clock,
+		limits:         limits,
+		events:         events,
 	}
 }
 
-func (n *Notes) CreateNote(ctx context.Context, title string, content string) (id string, err error) {
+// CreateNote stores a new note. id is optional: when empty one is generated.
+func (n *Notes) CreateNote(ctx context.Context, id, title, content string, format models.ContentFormat) (string, error) {
 	const op = "services.notes.CreateNote"
-	log := n.log.With(slog.String("op", op))
-	id, err = n.noteCreator.CreateNote(ctx, title, content)
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op))
+	var v validate.Violations
+	v.UUID("id", id, false)
+	v.Title("title", title, true, n.limits.MaxTitleLength)
+	v.Text("content", content, n.limits.MaxContentBytes)
+	if err := v.Err(); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	id = strings.ToLower(id)
+	if format == "" {
+		format = models.ContentFormatPlain
+	}
+	if id == "" {
+		var err error
+		id, err = n.ids.NewID()
+		if err != nil {
+			log.Error("failed to generate id", slog.String("err", err.Error()))
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	id, err := n.noteCreator.CreateNote(ctx, models.Note{
+		Id:            id,
+		Title:         title,
+		Content:       content,
+		ContentFormat: format,
+	})
 	if err != nil {
-		log.Warn("err:" + err.Error())
+		if errors.Is(err, storage.IdAlreadyExists) {
+			log.Warn("Id already exists", slog.String("err", err.Error()))
+		} else {
+			log.Warn("err:" + err.Error())
+		}
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
+	n.events.NotesCreated(OriginCreate, 1)
 	log.Info("Note created", slog.Any("id", id))
-	return
+	return id, nil
+
+}
 
+// CreateNoteFromHtml converts a clipped web page to a Markdown note.
+// When title is empty it is taken from the page, falling back to the source url.
+func (n *Notes) CreateNoteFromHtml(ctx context.Context, title, pageHtml string, sourceURL *url.URL) (models.Note, error) {
+	const op = "services.notes.CreateNoteFromHtml"
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op))
+	var v validate.Violations
+	v.Title("title", title, false, n.limits.MaxTitleLength)
+	v.Html("html", pageHtml, n.limits.MaxContentBytes)
+	if err := v.Err(); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// Real pages often carry stray control characters such as form feeds,
+	// so they are replaced instead of rejecting the whole page.
+	page, err := markdown.Clip(stripControl(pageHtml), sourceURL)
+	if err != nil {
+		log.Warn("failed to parse html", slog.String("err", err.Error()))
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
+	v.Text("content", page.Content, n.limits.MaxContentBytes)
+	if err := v.Err(); err != nil {
+		log.Warn("invalid clipped content", slog.String("err", err.Error()))
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
+	note := models.Note{
+		Title:         title,
+		Content:       page.Content,
+		ContentFormat: models.ContentFormatMarkdown,
+		SourceUrl:     sourceURL.String(),
+	}
+	if note.Title == "" {
+		note.Title = page.Title
+	}
+	if note.Title == "" {
+		note.Title = sourceURL.Host + sourceURL.Path
+	}
+	note.Title = truncate(note.Title, n.limits.MaxTitleLength)
+	note.Id, err = n.ids.NewID()
+	if err != nil {
+		log.Error("failed to generate id", slog.String("err", err.Error()))
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
+	note.Id, err = n.noteCreator.CreateNote(ctx, note)
+	if err != nil {
+		log.Warn("err:" + err.Error())
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
+	n.events.NotesCreated(OriginClip, 1)
+	log.Info("Note clipped", slog.String("id", note.Id), slog.String("source_url", note.SourceUrl))
+	return note, nil
 }
+
 func (n *Notes) GetNoteById(ctx context.Context, id string) (models.Note, error) {
 	const op = "services.notes.GetNoteById"
-	log := n.log.With(slog.String("op", op))
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op))
+	if err := validateId(id); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
 	note, err := n.noteGetterById.GetNoteById(ctx, id)
 	if err != nil {
 		if errors.Is(err, storage.IdNotFound) {
@@ -87,10 +252,20 @@ func (n *Notes) GetNoteById(ctx context.Context, id string) (models.Note, error)
 	return note, nil
 
 }
-func (n *Notes) UpdateNote(ctx context.Context, id, title, content string) (models.Note, error) {
+func (n *Notes) UpdateNote(ctx context.Context, id, title, content string, format models.ContentFormat) (models.Note, error) {
 	const op = "services.notes.UpdateNote"
-	log := n.log.With(slog.String("op", op))
-	note, err := n.noteUpdater.UpdateNote(ctx, id, title, content)
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op))
+	var v validate.Violations
+	v.UUID("id", id, true)
+	v.Title("title", title, true, n.limits.MaxTitleLength)
+	v.Text("content", content, n.limits.MaxContentBytes)
+	if err := v.Err(); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
+	note, err := n.noteUpdater.UpdateNote(ctx, id, title, content, format)
 	if err != nil {
 		if errors.Is(err, storage.IdNotFound) {
 			log.Warn("Id not found", slog.String("err", err.Error()))
@@ -102,7 +277,13 @@ func (n *Notes) UpdateNote(ctx context.Context, id, title, content string) (mode
 }
 func (n *Notes) DeleteNote(ctx context.Context, id string) (models.Note, error) {
 	const op = "services.notes.DeleteNote"
-	log := n.log.With(slog.String("op", op))
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op))
+	if err := validateId(id); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+	}
 	note, err := n.noteDeleter.DeleteNote(ctx, id)
 	if err != nil {
 		if errors.Is(err, storage.IdNotFound) {
@@ -110,7 +291,8 @@ func (n *Notes) DeleteNote(ctx context.Context, id string) (models.Note, error)
 		}
 		return note, fmt.Errorf("%s: %w", op, err)
 	}
-	log.Info("Note deleted ", slog.Any("note", note))
+	n.events.NotesDeleted(1)
+	log.Info("Note deleted", slog.Any("note", note))
 	return note, nil
 }
 func (n *Notes) GetNotes(
@@ -118,7 +300,16 @@ func (n *Notes) GetNotes(
 	limit int32,
 	offset_id string) (notes []models.Note, next_offset_id string, err error) {
 	const op = "services.notes.GetNotes"
-	log := n.log.With(slog.String("op", op))
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(attribute.Int("limit", int(limit))))
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op))
+	var v validate.Violations
+	v.UUID("offset_id", offset_id, true)
+	v.PageSize("limit", limit, n.limits.MaxPageSize)
+	if err := v.Err(); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
 	notes, next_offset_id, err = n.noteLister.GetNotes(ctx, limit, offset_id)
 	if err != nil {
 		if errors.Is(err, storage.IdNotFound) {
@@ -126,6 +317,189 @@ func (n *Notes) GetNotes(
 		}
 		return nil, "", fmt.Errorf("%s: %w", op, err)
 	}
-	log.Info("Notes received ", slog.Any("notes", notes))
+	log.Info("Notes received", slog.Int("count", len(notes)))
 	return notes, next_offset_id, nil
 }
+
+// RenderNote returns a note together with its content rendered to sanitized HTML.
+func (n *Notes) RenderNote(ctx context.Context, id string) (models.Note, models.RenderedContent, error) {
+	const op = "services.notes.RenderNote"
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op))
+	if err := validateId(id); err != nil {
+		log.Warn("invalid request", slog.String("err", err.Error()))
+		return models.Note{}, models.RenderedContent{}, fmt.Errorf("%s: %w", op, err)
+	}
+	note, err := n.noteGetterById.GetNoteById(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.IdNotFound) {
+			log.Warn("Id not found", slog.String("err", err.Error()))
+		}
+		return note, models.RenderedContent{}, fmt.Errorf("%s: %w", op, err)
+	}
+	rendered, err := n.noteRenderer.Render(note.ContentFormat, note.Content)
+	if err != nil {
+		log.Error("failed to render note", slog.String("err", err.Error()))
+		return note, models.RenderedContent{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("Note rendered", slog.String("id", note.Id))
+	return note, rendered, nil
+}
+
+// ErrTooManyImportNotes is returned once an import sends more than
+// Limits.MaxImportNotes notes.
+var ErrTooManyImportNotes = errs.New(errs.QuotaExceeded, "TOO_MANY_IMPORT_NOTES", "import has too many notes")
+
+// ImportNotes validates the notes returned by next until it reports io.EOF,
+// writing them in batches of importBatchSize as they arrive, so an import is
+// never held in memory whole. A failed batch does not abort the call: its
+// notes and the ones after it are reported as failed, while earlier batches
+// stay committed. The same holds when next fails or the import grows past
+// the limit, except that the call then returns the error.
+func (n *Notes) ImportNotes(ctx context.Context, next func() (models.ImportNote, error), dryRun bool) ([]models.ImportResult, error) {
+	const op = "services.notes.ImportNotes"
+	ctx, span := tracer.Start(ctx, op, trace.WithAttributes(attribute.Bool("dry_run", dryRun)))
+	defer span.End()
+	log := logging.FromContext(ctx, n.log).With(slog.String("op", op), slog.Bool("dry_run", dryRun))
+
+	var results []models.ImportResult
+	seen := make(map[string]string)
+	batch := make([]models.ImportNote, 0, importBatchSize)
+	var failed error
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if failed != nil {
+			for _, note := range batch {
+				results = append(results, importFailure(note, "not imported: previous batch failed"))
+			}
+			batch = batch[:0]
+			return
+		}
+		imported, err := n.noteImporter.ImportNotes(ctx, batch, dryRun)
+		if err != nil {
+			log.Error("failed to import batch", slog.String("err", err.Error()))
+			failed = err
+			for _, note := range batch {
+				results = append(results, importFailure(note, "batch failed: "+err.Error()))
+			}
+		} else {
+			results = append(results, imported...)
+			if !dryRun {
+				n.events.NotesCreated(OriginImport, countImported(imported))
+			}
+		}
+		batch = batch[:0]
+	}
+
+	total := 0
+	for {
+		note, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Warn("import interrupted", slog.Int("received", total), slog.String("err", err.Error()))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		total++
+		if n.limits.MaxImportNotes > 0 && total > n.limits.MaxImportNotes {
+			log.Warn("import has too many notes", slog.Int("max", n.limits.MaxImportNotes))
+			return nil, fmt.Errorf("%s: %w", op, ErrTooManyImportNotes)
+		}
+		if err := n.validateImportNote(note); err != nil {
+			results = append(results, importFailure(note, err.Error()))
+			continue
+		}
+		// Notes repeated inside one import are caught here, which also keeps
+		// dry runs accurate since their batches are never committed.
+		hash := models.ContentHash(note.Content)
+		if id, ok := seen[hash]; ok {
+			results = append(results, models.ImportResult{Source: note.Source, Id: id, Status: models.ImportStatusDuplicate})
+			continue
+		}
+		if note.Id != "" {
+			if _, ok := seen[note.Id]; ok {
+				results = append(results, models.ImportResult{Source: note.Source, Id: note.Id, Status: models.ImportStatusDuplicate})
+				continue
+			}
+			seen[note.Id] = note.Id
+		} else {
+			id, err := n.ids.NewID()
+			if err != nil {
+				results = append(results, importFailure(note, "failed to generate id"))
+				continue
+			}
+			note.Id = id
+		}
+		if note.CreatedAt.IsZero() {
+			note.CreatedAt = n.clock.Now()
+		}
+		seen[hash] = note.Id
+
+		batch = append(batch, note)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	span.SetAttributes(attribute.Int("notes", total))
+	log.Info("Notes imported", slog.Int("total", total), slog.Bool("failed", failed != nil))
+	return results, nil
+}
+
+func countImported(results []models.ImportResult) int {
+	count := 0
+	for _, r := range results {
+		if r.Status == models.ImportStatusImported {
+			count++
+		}
+	}
+	return count
+}
+
+func (n *Notes) validateImportNote(note models.ImportNote) error {
+	var v validate.Violations
+	v.UUID("id", note.Id, false)
+	v.Title("title", note.Title, true, n.limits.MaxTitleLength)
+	v.Text("content", note.Content, n.limits.MaxContentBytes)
+	return v.Err()
+}
+
+func validateId(id string) error {
+	var v validate.Violations
+	v.UUID("id", id, true)
+	return v.Err()
+}
+
+// truncate cuts s to at most max characters; max <= 0 keeps s whole.
+func truncate(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
+
+// stripControl replaces control characters other than tabs and line breaks
+// with spaces, so words they separated stay apart.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r != '\t' && r != '\n' && r != '\r' && unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+func importFailure(note models.ImportNote, msg string) models.ImportResult {
+	return models.ImportResult{
+		Source:  note.Source,
+		Id:      note.Id,
+		Status:  models.ImportStatusFailed,
+		Message: msg,
+	}
+}
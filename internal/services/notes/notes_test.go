@@ -0,0 +1,95 @@
+package notes_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/lib/fakes"
+	"github.com/crewblade/notes_service/internal/lib/validate"
+	"github.com/crewblade/notes_service/internal/services/notes"
+	"github.com/crewblade/notes_service/internal/services/notes/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newImportService(t *testing.T, importer *mocks.NoteImporter, limits validate.Limits) *notes.Notes {
+	t.Helper()
+	events := mocks.NewEvents(t)
+	events.On("NotesCreated", notes.OriginImport, mock.Anything).Maybe()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clock := fakes.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	return notes.New(log, nil, nil, nil, nil, nil, importer, nil, fakes.NewIDGenerator(), clock, limits, events)
+}
+
+// importSource yields count distinct notes and counts how many were taken.
+func importSource(count int, taken *int) func() (models.ImportNote, error) {
+	return func() (models.ImportNote, error) {
+		if *taken == count {
+			return models.ImportNote{}, io.EOF
+		}
+		*taken++
+		return models.ImportNote{Title: "title", Content: fmt.Sprintf("content %d", *taken)}, nil
+	}
+}
+
+func imported(_ context.Context, batch []models.ImportNote, _ bool) ([]models.ImportResult, error) {
+	results := make([]models.ImportResult, 0, len(batch))
+	for _, note := range batch {
+		results = append(results, models.ImportResult{Id: note.Id, Status: models.ImportStatusImported})
+	}
+	return results, nil
+}
+
+func TestImportNotesWritesBatchesAsTheyArrive(t *testing.T) {
+	var taken int
+	var takenAtWrite []int
+	importer := mocks.NewNoteImporter(t)
+	importer.On("ImportNotes", mock.Anything, mock.Anything, false).
+		Run(func(mock.Arguments) { takenAtWrite = append(takenAtWrite, taken) }).
+		Return(imported)
+	s := newImportService(t, importer, validate.Limits{})
+
+	results, err := s.ImportNotes(context.Background(), importSource(250, &taken), false)
+	require.NoError(t, err)
+
+	assert.Len(t, results, 250)
+	// The first batches go out before the rest of the import is received.
+	assert.Equal(t, []int{100, 200, 250}, takenAtWrite)
+}
+
+func TestImportNotesTooManyNotes(t *testing.T) {
+	var taken int
+	importer := mocks.NewNoteImporter(t)
+	importer.On("ImportNotes", mock.Anything, mock.Anything, false).Return(imported).Once()
+	s := newImportService(t, importer, validate.Limits{MaxImportNotes: 150})
+
+	_, err := s.ImportNotes(context.Background(), importSource(250, &taken), false)
+
+	require.ErrorIs(t, err, notes.ErrTooManyImportNotes)
+	assert.Equal(t, 151, taken)
+}
+
+func TestCreateNoteFromHtmlStripsControlCharacters(t *testing.T) {
+	creator := mocks.NewNoteCreator(t)
+	creator.On("CreateNote", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, note models.Note) (string, error) { return note.Id, nil })
+	events := mocks.NewEvents(t)
+	events.On("NotesCreated", notes.OriginClip, 1)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clock := fakes.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := notes.New(log, creator, nil, nil, nil, nil, nil, nil, fakes.NewIDGenerator(), clock, validate.Limits{}, events)
+	source, err := url.Parse("https://example.com/page")
+	require.NoError(t, err)
+
+	note, err := s.CreateNoteFromHtml(context.Background(), "", "<html><body><p>one\x0btwo\x0cthree</p></body></html>", source)
+	require.NoError(t, err)
+
+	assert.Equal(t, "one two three", strings.TrimSpace(note.Content))
+}
@@ -0,0 +1,176 @@
+package localfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/storage"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Storage keeps blobs on the local filesystem, addressed by the SHA-256 of
+// their content: <root>/ab/cd/abcd...; identical uploads share one file.
+type Storage struct {
+	root string
+}
+
+const tmpDir = "tmp"
+
+func New(root string) (*Storage, error) {
+	const op = "storage.localfs.New"
+	if err := os.MkdirAll(filepath.Join(root, tmpDir), 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &Storage{root: root}, nil
+}
+
+// Put writes the content of r and returns its hash and size. The content is
+// written to a temporary file first, so a failed read leaves nothing behind.
+func (s *Storage) Put(ctx context.Context, r io.Reader) (hash string, size int64, err error) {
+	const op = "storage.localfs.Put"
+
+	tmp, err := os.CreateTemp(filepath.Join(s.root, tmpDir), "upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, h), contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	hash = hex.EncodeToString(h.Sum(nil))
+
+	path := s.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		// Already stored. Touch it so garbage collection sees a fresh blob
+		// until the new reference to it is saved.
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return "", 0, fmt.Errorf("%s: %w", op, err)
+		}
+		return hash, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return hash, size, nil
+}
+
+func (s *Storage) Open(ctx context.Context, hash string) (io.ReadCloser, error) {
+	const op = "storage.localfs.Open"
+	if !validHash(hash) {
+		return nil, fmt.Errorf("%s: %w", op, storage.BlobNotFound)
+	}
+	f, err := os.Open(s.path(hash))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, storage.BlobNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return f, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, hash string) error {
+	const op = "storage.localfs.Delete"
+	if !validHash(hash) {
+		return fmt.Errorf("%s: %w", op, storage.BlobNotFound)
+	}
+	if err := os.Remove(s.path(hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) Stat(ctx context.Context, hash string) (models.Blob, error) {
+	const op = "storage.localfs.Stat"
+	if !validHash(hash) {
+		return models.Blob{}, fmt.Errorf("%s: %w", op, storage.BlobNotFound)
+	}
+	info, err := os.Stat(s.path(hash))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return models.Blob{}, fmt.Errorf("%s: %w", op, storage.BlobNotFound)
+		}
+		return models.Blob{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return models.Blob{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Blobs lists every stored blob.
+func (s *Storage) Blobs(ctx context.Context) ([]models.Blob, error) {
+	const op = "storage.localfs.Blobs"
+	var blobs []models.Blob
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == tmpDir && filepath.Dir(path) == s.root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !validHash(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, models.Blob{Hash: d.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return blobs, nil
+}
+
+func (s *Storage) path(hash string) string {
+	return filepath.Join(s.root, hash[:2], hash[2:4], hash)
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// contextReader stops a long copy once the request is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
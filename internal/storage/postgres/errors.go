@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgInvalidTextRepresentation = "22P02"
+	// pgForeignKeyViolation is raised when an attachment refers to a missing note.
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+	// pgQueryCanceled is raised when statement_timeout fires.
+	pgQueryCanceled = "57014"
+)
+
+// mapError translates pgx errors into storage errors. Errors without a
+// domain meaning are returned unchanged.
+func mapError(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.IdNotFound
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return storage.IdAlreadyExists
+	case pgForeignKeyViolation:
+		return storage.IdNotFound
+	case pgInvalidTextRepresentation:
+		// A malformed uuid cannot match any row.
+		return storage.IdNotFound
+	case pgQueryCanceled:
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return err
+}
@@ -2,160 +2,565 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/crewblade/notes_service/internal/domain/models"
-	"github.com/crewblade/notes_service/internal/storage"
-	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/crewblade/notes_service/internal/lib/ratelimit"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log/slog"
+	"strconv"
 	"time"
 )
 
 type Storage struct {
-	db *sql.DB
+	pool   *pgxpool.Pool
+	router *router
+	retry  *retrier
+	clock  Clock
 }
 
-func New(connectionString string) (*Storage, error) {
+// Options tune the connection pool. Zero values keep the pgxpool defaults.
+type Options struct {
+	MaxOpenConns int
+	// MaxIdleConns connections are kept open even when the pool is idle.
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// StatementTimeout is sent to the server as statement_timeout for every session.
+	StatementTimeout time.Duration
+
+	// Replicas are connection strings of read replicas used for get and list queries.
+	Replicas []string
+	// ReplicaMaxLag takes a replica out of rotation while it lags further behind.
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+	// ReadYourWritesWindow keeps a caller on the primary for this long after it writes.
+	ReadYourWritesWindow time.Duration
+
+	// RetryMaxAttempts bounds attempts per operation on transient errors; 1 disables retries.
+	RetryMaxAttempts int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+
+	// Observer, if set, is told how long each operation took, retries included.
+	Observer QueryObserver
+}
+
+type QueryObserver interface {
+	ObserveQuery(op string, duration time.Duration, err error)
+}
+
+type Clock interface {
+	Now() time.Time
+}
+
+func New(log *slog.Logger, connectionString string, opts Options, clock Clock) (*Storage, error) {
 	const op = "storage.postgres.New"
-	db, err := sql.Open("postgres", connectionString)
+	pool, err := newPool(connectionString, opts)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-
-	//	stmt, err := db.Prepare(`
-	//	CREATE TABLE IF NOT EXISTS notes (
-	//		id UUID PRIMARY KEY,
-	//		title TEXT NOT NULL,
-	//		content TEXT,
-	//		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
-	//	)
-	//`)
-	//	if err != nil {
-	//		return nil, fmt.Errorf("%s, %w", op, err)
-	//	}
-	//
-	//	_, err = stmt.Exec()
-	//	if err != nil {
-	//		return nil, fmt.Errorf("%s, %w", op, err)
-	//	}
-	return &Storage{db: db}, nil
-
+	replicas := make([]*pgxpool.Pool, 0, len(opts.Replicas))
+	for _, dsn := range opts.Replicas {
+		replica, err := newPool(dsn, opts)
+		if err != nil {
+			for _, r := range replicas {
+				r.Close()
+			}
+			pool.Close()
+			return nil, fmt.Errorf("%s: replica: %w", op, err)
+		}
+		replicas = append(replicas, replica)
+	}
+	return &Storage{
+		pool:   pool,
+		router: newRouter(pool, replicas, opts, clock),
+		retry:  newRetrier(log, opts),
+		clock:  clock,
+	}, nil
 }
 
-func (s *Storage) CreateNote(ctx context.Context, title string, content string) (id string, err error) {
-	const op = "storage.postgres.CreateNote"
-	id = uuid.NewString()
-	createdAt := time.Now()
-	stmt, err := s.db.Prepare("INSERT INTO notes(id, title, content, created_at) VALUES ($1, $2, $3, $4)")
+func newPool(connectionString string, opts Options) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(connectionString)
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return nil, err
+	}
+	if opts.MaxOpenConns > 0 {
+		cfg.MaxConns = int32(opts.MaxOpenConns)
 	}
-	_, err = stmt.ExecContext(ctx, id, title, content, createdAt)
+	if opts.MaxIdleConns > 0 {
+		cfg.MinConns = min(int32(opts.MaxIdleConns), cfg.MaxConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		cfg.MaxConnLifetime = opts.ConnMaxLifetime
+	}
+	if opts.StatementTimeout > 0 {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
+	cfg.ConnConfig.Tracer = queryTracer{}
+	return pgxpool.NewWithConfig(context.Background(), cfg)
+}
+
+func (s *Storage) CreateNote(ctx context.Context, note models.Note) (id string, err error) {
+	const op = "storage.postgres.CreateNote"
+	id = note.Id
+	createdAt := s.clock.Now()
+	// Not idempotent: a rerun after an unknown commit would report the note
+	// it created as already existing.
+	err = s.retry.do(ctx, op, false, func(ctx context.Context) error {
+		_, err := s.pool.Exec(ctx, qCreateNote,
+			id, note.Title, note.Content, note.ContentFormat, models.ContentHash(note.Content), note.SourceUrl, createdAt)
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+		return "", fmt.Errorf("%s: %w", op, mapError(err))
 	}
+	s.router.wrote(ctx)
 	return id, nil
 
 }
 func (s *Storage) GetNoteById(ctx context.Context, id string) (models.Note, error) {
 	const op = "storage.postgres.GetNoteById"
-	stmt, err := s.db.Prepare("SELECT id, title, content FROM notes WHERE id = $1")
-	if err != nil {
-		return models.Note{}, fmt.Errorf("%s: %w", op, err)
-	}
-	row := stmt.QueryRowContext(ctx, id)
 	var note models.Note
-	err = row.Scan(&note.Id, &note.Title, &note.Content)
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		return s.router.reader(ctx).QueryRow(ctx, qGetNoteById, id).
+			Scan(&note.Id, &note.Title, &note.Content, &note.ContentFormat, &note.Tags, &note.SourceUrl)
+	})
 	if err != nil {
-		return models.Note{}, fmt.Errorf("%s: %w", op, storage.IdNotFound)
+		return models.Note{}, fmt.Errorf("%s: %w", op, mapError(err))
 	}
 
 	return note, nil
 }
-func (s *Storage) UpdateNote(ctx context.Context, id, title, content string) (models.Note, error) {
-	const op = "storage.postgres.UpdateNote"
-
-	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1)", id).Scan(&exists)
-	if err != nil || !exists {
-		return models.Note{}, fmt.Errorf("%s: %w", op, storage.IdNotFound)
-	}
 
-	_, err = s.db.ExecContext(ctx, "UPDATE notes SET title = $1, content = $2 WHERE id = $3", title, content, id)
-	if err != nil {
-		return models.Note{}, fmt.Errorf("%s: %w", op, err)
-	}
+// UpdateNote replaces title and content of a note. An empty format keeps the current one.
+// It runs as a single statement, so a concurrent delete yields IdNotFound
+// instead of a partial update or a stale read.
+func (s *Storage) UpdateNote(ctx context.Context, id, title, content string, format models.ContentFormat) (models.Note, error) {
+	const op = "storage.postgres.UpdateNote"
 
 	var updatedNote models.Note
-	err = s.db.QueryRowContext(ctx, "SELECT id, title, content FROM notes WHERE id = $1", id).Scan(&updatedNote.Id, &updatedNote.Title, &updatedNote.Content)
+	updatedAt := s.clock.Now()
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		return s.pool.QueryRow(ctx, qUpdateNote,
+			title, content, format, models.ContentHash(content), updatedAt, id,
+		).Scan(&updatedNote.Id, &updatedNote.Title, &updatedNote.Content, &updatedNote.ContentFormat, &updatedNote.Tags, &updatedNote.SourceUrl)
+	})
 	if err != nil {
-		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+		return models.Note{}, fmt.Errorf("%s: %w", op, mapError(err))
 	}
 
+	s.router.wrote(ctx)
 	return updatedNote, nil
 }
+
 func (s *Storage) DeleteNote(ctx context.Context, id string) (models.Note, error) {
 	const op = "storage.postgres.DeleteNote"
 
-	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM notes WHERE id = $1)", id).Scan(&exists)
-	if err != nil || !exists {
-		return models.Note{}, fmt.Errorf("%s: %w", op, storage.IdNotFound)
-	}
 	var deletedNote models.Note
-	err = s.db.QueryRowContext(ctx, "SELECT id, title, content FROM notes WHERE id = $1", id).Scan(&deletedNote.Id, &deletedNote.Title, &deletedNote.Content)
+	// Not idempotent: a rerun after an unknown commit would report the note
+	// it deleted as not found.
+	err := s.retry.do(ctx, op, false, func(ctx context.Context) error {
+		return s.pool.QueryRow(ctx, qDeleteNote, id).
+			Scan(&deletedNote.Id, &deletedNote.Title, &deletedNote.Content, &deletedNote.ContentFormat, &deletedNote.Tags, &deletedNote.SourceUrl)
+	})
 	if err != nil {
-		return models.Note{}, fmt.Errorf("%s: %w", op, err)
-	}
-
-	_, err = s.db.ExecContext(ctx, "DELETE FROM notes WHERE id = $1", id)
-	if err != nil {
-		return models.Note{}, fmt.Errorf("%s: %w", op, err)
+		return models.Note{}, fmt.Errorf("%s: %w", op, mapError(err))
 	}
 
+	s.router.wrote(ctx)
 	return deletedNote, nil
 }
 
 func (s *Storage) GetNotes(ctx context.Context, limit int32, offsetID string) ([]models.Note, string, error) {
 	const op = "storage.postgres.GetNotes"
 
-	var offsetTime time.Time
-	err := s.db.QueryRowContext(ctx, "SELECT created_at FROM notes WHERE id = $1", offsetID).Scan(&offsetTime)
+	var notes []models.Note
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		var err error
+		notes, err = s.listNotes(ctx, limit, offsetID)
+		return err
+	})
 	if err != nil {
-		//if errors.Is(err, sql.ErrNoRows) {
-		//	return nil, "", fmt.Errorf("%s: %w", op, storage.IdNotFound)
-		//}
-		//return nil, "", fmt.Errorf("%s: %w", op, err)
-		return nil, "", fmt.Errorf("%s: %w", op, storage.IdNotFound)
+		return nil, "", fmt.Errorf("%s: %w", op, mapError(err))
 	}
-	rows, err := s.db.QueryContext(ctx, "SELECT id, title, content FROM notes WHERE created_at >= $1 ORDER BY created_at LIMIT $2", offsetTime, limit+1)
+
+	var nextOffsetID string
+	// (1 2 3) 4| 5
+	// limit 3 + 1
+	if len(notes) == int(limit)+1 {
+		nextOffsetID = notes[len(notes)-1].Id
+		notes = notes[:len(notes)-1]
+	}
+	return notes, nextOffsetID, nil
+}
+
+// listNotes returns up to limit+1 notes starting at offsetID in a single
+// round trip. Notes created at the same instant are ordered by id, so pages
+// neither repeat nor skip them.
+func (s *Storage) listNotes(ctx context.Context, limit int32, offsetID string) ([]models.Note, error) {
+	rows, err := s.router.reader(ctx).Query(ctx, qListNotes, offsetID, limit+1)
 	if err != nil {
-		return nil, "", fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
+	defer rows.Close()
 
 	var notes []models.Note
-	var nextOffsetID string
 	for rows.Next() {
 		var note models.Note
-		if err := rows.Scan(&note.Id, &note.Title, &note.Content); err != nil {
-			fmt.Println(err.Error())
-			return nil, "", fmt.Errorf("%s: %w", op, err)
+		if err := rows.Scan(&note.Id, &note.Title, &note.Content, &note.ContentFormat, &note.Tags, &note.SourceUrl); err != nil {
+			return nil, err
 		}
 		notes = append(notes, note)
-		nextOffsetID = note.Id
 	}
-	// (1 2 3) 4| 5
-	// limit 3 + 1
-	if len(notes) == int(limit)+1 {
-		notes = notes[:len(notes)-1]
-	} else {
-		nextOffsetID = ""
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return notes, nextOffsetID, nil
+	// The offset note always starts the page. For an unknown offset the
+	// subquery yields NULL and nothing matches.
+	if len(notes) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return notes, nil
+}
+
+// ImportNotes inserts notes in a single transaction, skipping the ones whose id
+// or content hash is already present. In dry run mode the transaction is rolled back.
+// Duplicate lookups and inserts are each sent as one pipelined batch.
+// Imports are deduplicated, so a retry after an unknown commit outcome reports
+// the notes as duplicates instead of importing them twice.
+func (s *Storage) ImportNotes(ctx context.Context, notes []models.ImportNote, dryRun bool) ([]models.ImportResult, error) {
+	const op = "storage.postgres.ImportNotes"
+
+	var results []models.ImportResult
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		var err error
+		results, err = s.importNotes(ctx, notes, dryRun)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapError(err))
+	}
+	if !dryRun {
+		s.router.wrote(ctx)
+	}
+	return results, nil
 }
+
+func (s *Storage) importNotes(ctx context.Context, notes []models.ImportNote, dryRun bool) ([]models.ImportResult, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	lookups := &pgx.Batch{}
+	for _, note := range notes {
+		lookups.Queue(qFindDuplicate, nullString(note.Id), models.ContentHash(note.Content))
+	}
+	duplicates := make([]string, len(notes))
+	br := tx.SendBatch(ctx, lookups)
+	for i := range notes {
+		err := br.QueryRow().Scan(&duplicates[i])
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			br.Close()
+			return nil, err
+		}
+	}
+	if err := br.Close(); err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ImportResult, 0, len(notes))
+	inserts := &pgx.Batch{}
+	seenIds := make(map[string]bool)
+	seenHashes := make(map[string]string)
+	for i, note := range notes {
+		result := models.ImportResult{Source: note.Source, Id: note.Id, Status: models.ImportStatusDuplicate}
+		hash := models.ContentHash(note.Content)
+		if duplicates[i] != "" {
+			result.Id = duplicates[i]
+			results = append(results, result)
+			continue
+		}
+		if id, ok := seenHashes[hash]; ok {
+			result.Id = id
+			results = append(results, result)
+			continue
+		}
+		if seenIds[note.Id] {
+			results = append(results, result)
+			continue
+		}
+
+		createdAt := note.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = s.clock.Now()
+		}
+		tags := note.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		format := note.Format
+		if format == "" {
+			format = models.ContentFormatPlain
+		}
+		inserts.Queue(qImportNote,
+			result.Id, note.Title, note.Content, format, hash, tags, createdAt, nullTime(note.UpdatedAt))
+		seenIds[result.Id] = true
+		seenHashes[hash] = result.Id
+		result.Status = models.ImportStatusImported
+		results = append(results, result)
+	}
+	if inserts.Len() > 0 {
+		if err := tx.SendBatch(ctx, inserts).Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return results, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// PoolStat describes the primary connection pool.
+func (s *Storage) PoolStat() *pgxpool.Stat {
+	return s.pool.Stat()
+}
+
+// RetryCounts returns the number of transient error retries per operation.
+func (s *Storage) RetryCounts() map[string]int64 {
+	return s.retry.Counts()
+}
+
+// Ping checks that the primary accepts queries.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgres.Ping"
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
 func (s *Storage) Close() error {
-	if s.db != nil {
-		return s.db.Close()
+	if s.router != nil {
+		s.router.close()
+	}
+	if s.pool != nil {
+		s.pool.Close()
+	}
+	return nil
+}
+
+func (s *Storage) SaveAttachment(ctx context.Context, attachment models.Attachment) (models.Attachment, error) {
+	const op = "storage.postgres.SaveAttachment"
+	attachment.CreatedAt = s.clock.Now()
+	// Not idempotent, for the same reason as CreateNote.
+	err := s.retry.do(ctx, op, false, func(ctx context.Context) error {
+		_, err := s.pool.Exec(ctx, qSaveAttachment,
+			attachment.Id, attachment.NoteId, attachment.FileName, attachment.MimeType, attachment.Size, attachment.Sha256, attachment.CreatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, mapError(err))
+	}
+	s.router.wrote(ctx)
+	return attachment, nil
+}
+
+func (s *Storage) GetAttachment(ctx context.Context, id string) (models.Attachment, error) {
+	const op = "storage.postgres.GetAttachment"
+	var a models.Attachment
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		return s.router.reader(ctx).QueryRow(ctx, qGetAttachment, id).
+			Scan(&a.Id, &a.NoteId, &a.FileName, &a.MimeType, &a.Size, &a.Sha256, &a.CreatedAt)
+	})
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, mapError(err))
+	}
+	return a, nil
+}
+
+func (s *Storage) ListAttachments(ctx context.Context, noteId string) ([]models.Attachment, error) {
+	const op = "storage.postgres.ListAttachments"
+	var attachments []models.Attachment
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		rows, err := s.router.reader(ctx).Query(ctx, qListAttachments, noteId)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		attachments = nil
+		for rows.Next() {
+			var a models.Attachment
+			if err := rows.Scan(&a.Id, &a.NoteId, &a.FileName, &a.MimeType, &a.Size, &a.Sha256, &a.CreatedAt); err != nil {
+				return err
+			}
+			attachments = append(attachments, a)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, mapError(err))
+	}
+	return attachments, nil
+}
+
+func (s *Storage) DeleteAttachment(ctx context.Context, id string) (models.Attachment, error) {
+	const op = "storage.postgres.DeleteAttachment"
+	var a models.Attachment
+	// Not idempotent, for the same reason as DeleteNote.
+	err := s.retry.do(ctx, op, false, func(ctx context.Context) error {
+		return s.pool.QueryRow(ctx, qDeleteAttachment, id).
+			Scan(&a.Id, &a.NoteId, &a.FileName, &a.MimeType, &a.Size, &a.Sha256, &a.CreatedAt)
+	})
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("%s: %w", op, mapError(err))
+	}
+	s.router.wrote(ctx)
+	return a, nil
+}
+
+// BlobReferenced reports whether any attachment still points to the blob.
+// It always reads the primary: a lagging replica could let GC delete a live blob.
+func (s *Storage) BlobReferenced(ctx context.Context, hash string) (bool, error) {
+	const op = "storage.postgres.BlobReferenced"
+	var exists bool
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		return s.pool.QueryRow(ctx, qBlobReferenced, hash).Scan(&exists)
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return exists, nil
+}
+
+// ReserveIdempotencyKey claims key for a request from callerId. When the
+// caller already holds the key and it has not expired, the existing record is returned with reserved == false.
+// The claim and the read of the current record go out as one pipelined batch.
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, callerId, key, method, requestHash string, ttl time.Duration) (record models.IdempotencyRecord, reserved bool, err error) {
+	const op = "storage.postgres.ReserveIdempotencyKey"
+	now := s.clock.Now()
+	batch := &pgx.Batch{}
+	batch.Queue(qReserveKey, callerId, key, method, requestHash, now, now.Add(ttl))
+	batch.Queue(qGetKey, callerId, key)
+
+	var tag pgconn.CommandTag
+	// Not idempotent: after an unknown outcome the retry would find its own
+	// reservation and report the request as in progress.
+	err = s.retry.do(ctx, op, false, func(ctx context.Context) error {
+		br := s.pool.SendBatch(ctx, batch)
+		var err error
+		tag, err = br.Exec()
+		if err == nil {
+			record.CallerId, record.Key = callerId, key
+			err = br.QueryRow().Scan(&record.Method, &record.RequestHash, &record.Response)
+		}
+		// The batch runs in one implicit transaction, so the claim only counts once it is closed cleanly.
+		if closeErr := br.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+	if err != nil {
+		return models.IdempotencyRecord{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+	return record, tag.RowsAffected() == 1, nil
+}
+
+// SaveIdempotentResponse completes a reserved key with the request hash and response.
+func (s *Storage) SaveIdempotentResponse(ctx context.Context, callerId, key, requestHash string, response []byte) error {
+	const op = "storage.postgres.SaveIdempotentResponse"
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		_, err := s.pool.Exec(ctx, qSaveResponse, requestHash, response, callerId, key)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops a reservation whose request failed, so it can be retried.
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, callerId, key string) error {
+	const op = "storage.postgres.ReleaseIdempotencyKey"
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		_, err := s.pool.Exec(ctx, qReleaseKey, callerId, key)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 	return nil
 }
+
+// TakeRateLimitToken takes a token from a bucket shared by every replica
+// using this database.
+func (s *Storage) TakeRateLimitToken(ctx context.Context, key string, limit ratelimit.Limit) (allowed bool, retryAfter time.Duration, err error) {
+	const op = "storage.postgres.TakeRateLimitToken"
+	now := s.clock.Now()
+	var tokens float64
+	err = s.retry.do(ctx, op, false, func(ctx context.Context) error {
+		return s.pool.QueryRow(ctx, qTakeToken, key, limit.Rate, limit.Capacity(), now).Scan(&tokens)
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if tokens < 1 {
+		return false, limit.Wait(tokens), nil
+	}
+	return true, 0, nil
+}
+
+// DeleteIdleRateLimits drops buckets unused for idle. A missing bucket counts
+// as full, so idle should be long enough for buckets to refill.
+func (s *Storage) DeleteIdleRateLimits(ctx context.Context, idle time.Duration) (int64, error) {
+	const op = "storage.postgres.DeleteIdleRateLimits"
+	var tag pgconn.CommandTag
+	before := s.clock.Now().Add(-idle)
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		var err error
+		tag, err = s.pool.Exec(ctx, qDeleteIdleRateLimits, before)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *Storage) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.DeleteExpiredIdempotencyKeys"
+	var tag pgconn.CommandTag
+	now := s.clock.Now()
+	err := s.retry.do(ctx, op, true, func(ctx context.Context) error {
+		var err error
+		tag, err = s.pool.Exec(ctx, qDeleteExpired, now)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
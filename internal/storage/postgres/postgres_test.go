@@ -0,0 +1,268 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/models"
+	"github.com/crewblade/notes_service/internal/lib/fakes"
+	"github.com/crewblade/notes_service/internal/migrator"
+	"github.com/crewblade/notes_service/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testDSNEnv names a database the tests are free to wipe. They are skipped
+// when it is not set.
+const testDSNEnv = "NOTES_TEST_POSTGRES_DSN"
+
+// unknownId is a well formed id the fake generator never hands out in a test.
+const unknownId = "00000000-0000-7000-8000-ffffffffffff"
+
+func newTestStorage(t testing.TB) (*Storage, *fakes.Clock, *fakes.IDGenerator) {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	require.NoError(t, migrator.Up(log, dsn, ""))
+
+	clock := fakes.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	s, err := New(log, dsn, Options{}, clock)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	_, err = s.pool.Exec(context.Background(), "TRUNCATE notes CASCADE")
+	require.NoError(t, err)
+	return s, clock, fakes.NewIDGenerator()
+}
+
+// createNotes creates a note per gap, advancing the clock by the gap first.
+// A zero gap gives the note the same created_at as the one before it.
+func createNotes(t testing.TB, s *Storage, clock *fakes.Clock, idGen *fakes.IDGenerator, gaps []time.Duration) []string {
+	t.Helper()
+	ids := make([]string, 0, len(gaps))
+	for _, gap := range gaps {
+		clock.Advance(gap)
+		id, err := idGen.NewID()
+		require.NoError(t, err)
+		_, err = s.CreateNote(context.Background(), models.Note{Id: id, Title: "title", Content: "content"})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestGetNotesPaging(t *testing.T) {
+	s, clock, idGen := newTestStorage(t)
+
+	tests := []struct {
+		name string
+		gaps []time.Duration
+		// offset and want index the created notes; -1 stands for unknownId
+		// and, in wantNext, for no next page.
+		offset   int
+		limit    int32
+		want     []int
+		wantNext int
+		wantErr  error
+	}{
+		{
+			name:    "empty page on an empty table",
+			offset:  -1,
+			limit:   3,
+			wantErr: storage.IdNotFound,
+		},
+		{
+			name:     "page exactly at the limit",
+			gaps:     []time.Duration{time.Second, time.Second, time.Second},
+			limit:    3,
+			want:     []int{0, 1, 2},
+			wantNext: -1,
+		},
+		{
+			name:     "limit plus one",
+			gaps:     []time.Duration{time.Second, time.Second, time.Second, time.Second},
+			limit:    3,
+			want:     []int{0, 1, 2},
+			wantNext: 3,
+		},
+		{
+			name:     "offset in the middle",
+			gaps:     []time.Duration{time.Second, time.Second, time.Second, time.Second},
+			offset:   2,
+			limit:    3,
+			want:     []int{2, 3},
+			wantNext: -1,
+		},
+		{
+			name:    "unknown offset_id",
+			gaps:    []time.Duration{time.Second, time.Second},
+			offset:  -1,
+			limit:   3,
+			wantErr: storage.IdNotFound,
+		},
+		{
+			name:     "ties on created_at",
+			gaps:     []time.Duration{time.Second, 0, 0, 0, time.Second},
+			offset:   1,
+			limit:    2,
+			want:     []int{1, 2},
+			wantNext: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.pool.Exec(context.Background(), "TRUNCATE notes CASCADE")
+			require.NoError(t, err)
+			ids := createNotes(t, s, clock, idGen, tt.gaps)
+			id := func(i int) string {
+				if i < 0 {
+					return unknownId
+				}
+				return ids[i]
+			}
+
+			notes, next, err := s.GetNotes(context.Background(), tt.limit, id(tt.offset))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			got := make([]string, 0, len(notes))
+			for _, note := range notes {
+				got = append(got, note.Id)
+			}
+			want := make([]string, 0, len(tt.want))
+			for _, i := range tt.want {
+				want = append(want, id(i))
+			}
+			assert.Equal(t, want, got)
+			if tt.wantNext < 0 {
+				assert.Empty(t, next)
+			} else {
+				assert.Equal(t, id(tt.wantNext), next)
+			}
+		})
+	}
+}
+
+func TestGetNotesWalksTies(t *testing.T) {
+	s, clock, idGen := newTestStorage(t)
+	ids := createNotes(t, s, clock, idGen, []time.Duration{time.Second, 0, 0, 0, 0, time.Second, 0})
+
+	var got []string
+	for offset := ids[0]; offset != ""; {
+		notes, next, err := s.GetNotes(context.Background(), 2, offset)
+		require.NoError(t, err)
+		for _, note := range notes {
+			got = append(got, note.Id)
+		}
+		offset = next
+	}
+	assert.Equal(t, ids, got)
+}
+
+func TestUpdateAndDeleteConcurrently(t *testing.T) {
+	s, _, idGen := newTestStorage(t)
+	ctx := context.Background()
+	id, err := idGen.NewID()
+	require.NoError(t, err)
+	_, err = s.CreateNote(ctx, models.Note{Id: id, Title: "title 0", Content: "content 0"})
+	require.NoError(t, err)
+
+	// Every writer stores a title and content with the same suffix, so a
+	// row mixing two writes shows up as a mismatch.
+	consistent := func(note models.Note) bool {
+		return note.Id == id &&
+			strings.TrimPrefix(note.Title, "title ") == strings.TrimPrefix(note.Content, "content ")
+	}
+
+	const writers = 16
+	var (
+		wg      sync.WaitGroup
+		deleted atomic.Int32
+	)
+	for i := 1; i <= writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var (
+				note models.Note
+				err  error
+			)
+			if i%2 == 0 {
+				note, err = s.DeleteNote(ctx, id)
+				if err == nil {
+					deleted.Add(1)
+				}
+			} else {
+				note, err = s.UpdateNote(ctx, id, fmt.Sprintf("title %d", i), fmt.Sprintf("content %d", i), "")
+			}
+			if err != nil {
+				assert.ErrorIs(t, err, storage.IdNotFound)
+				return
+			}
+			assert.True(t, consistent(note), "inconsistent note %+v", note)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, deleted.Load())
+	_, err = s.GetNoteById(ctx, id)
+	assert.ErrorIs(t, err, storage.IdNotFound)
+}
+
+// BenchmarkGetNoteById compares preparing the query on every call, which is
+// what the storage did before statements were prepared once, with the
+// per-connection statement cache pgx keeps today.
+func BenchmarkGetNoteById(b *testing.B) {
+	s, _, idGen := newTestStorage(b)
+	ctx := context.Background()
+	id, err := idGen.NewID()
+	require.NoError(b, err)
+	_, err = s.CreateNote(ctx, models.Note{Id: id, Title: "title", Content: "content"})
+	require.NoError(b, err)
+
+	modes := []struct {
+		name string
+		mode pgx.QueryExecMode
+	}{
+		{name: "prepare per call", mode: pgx.QueryExecModeDescribeExec},
+		{name: "cached statements", mode: pgx.QueryExecModeCacheStatement},
+	}
+	for _, m := range modes {
+		b.Run(m.name, func(b *testing.B) {
+			cfg, err := pgxpool.ParseConfig(os.Getenv(testDSNEnv))
+			require.NoError(b, err)
+			cfg.ConnConfig.DefaultQueryExecMode = m.mode
+			pool, err := pgxpool.NewWithConfig(ctx, cfg)
+			require.NoError(b, err)
+			defer pool.Close()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var note models.Note
+				for pb.Next() {
+					err := pool.QueryRow(ctx, qGetNoteById, id).
+						Scan(&note.Id, &note.Title, &note.Content, &note.ContentFormat, &note.Tags, &note.SourceUrl)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package postgres
+
+// pgx prepares and caches every query per connection on first use, so the
+// queries are kept here as plain constants.
+const (
+	noteColumns       = "id, title, content, content_format, tags, source_url"
+	attachmentColumns = "id, note_id, file_name, mime_type, size, sha256, created_at"
+
+	qCreateNote    = "INSERT INTO notes(id, title, content, content_format, content_hash, source_url, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+	qGetNoteById   = "SELECT " + noteColumns + " FROM notes WHERE id = $1"
+	qUpdateNote    = "UPDATE notes SET title = $1, content = $2, content_format = COALESCE(NULLIF($3, ''), content_format), content_hash = $4, updated_at = $5 WHERE id = $6 RETURNING " + noteColumns
+	qDeleteNote    = "DELETE FROM notes WHERE id = $1 RETURNING " + noteColumns
+	qListNotes     = "SELECT " + noteColumns + " FROM notes WHERE (created_at, id) >= ((SELECT created_at FROM notes WHERE id = $1), $1) ORDER BY created_at, id LIMIT $2"
+	qFindDuplicate = "SELECT id FROM notes WHERE id = $1 OR content_hash = $2 LIMIT 1"
+	qImportNote    = "INSERT INTO notes(id, title, content, content_format, content_hash, tags, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+
+	qSaveAttachment   = "INSERT INTO attachments(" + attachmentColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7)"
+	qGetAttachment    = "SELECT " + attachmentColumns + " FROM attachments WHERE id = $1"
+	qListAttachments  = "SELECT " + attachmentColumns + " FROM attachments WHERE note_id = $1 ORDER BY created_at"
+	qDeleteAttachment = "DELETE FROM attachments WHERE id = $1 RETURNING " + attachmentColumns
+	qBlobReferenced   = "SELECT EXISTS(SELECT 1 FROM attachments WHERE sha256 = $1)"
+
+	qReserveKey = `
+		INSERT INTO idempotency_keys(caller_id, key, method, request_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (caller_id, key) DO UPDATE
+			SET method = EXCLUDED.method, request_hash = EXCLUDED.request_hash, response = NULL,
+				created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at < $5`
+	qGetKey        = "SELECT method, request_hash, response FROM idempotency_keys WHERE caller_id = $1 AND key = $2"
+	qSaveResponse  = "UPDATE idempotency_keys SET request_hash = $1, response = $2 WHERE caller_id = $3 AND key = $4"
+	qReleaseKey    = "DELETE FROM idempotency_keys WHERE caller_id = $1 AND key = $2 AND response IS NULL"
+	qDeleteExpired = "DELETE FROM idempotency_keys WHERE expires_at < $1"
+
+	// qTakeToken refills the bucket for the time since its last use, takes a
+	// token if one is there and returns the tokens found before taking.
+	// Concurrent calls for one key wait on the row lock.
+	qTakeToken = `
+		WITH prev AS (
+			SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE
+		), cur AS (
+			SELECT COALESCE(
+				LEAST($3::float8, p.tokens + GREATEST(0, EXTRACT(EPOCH FROM $4::timestamptz - p.updated_at)::float8) * $2::float8),
+				$3::float8) AS tokens
+			FROM (SELECT 1) AS one LEFT JOIN prev p ON true
+		), upsert AS (
+			INSERT INTO rate_limits(key, tokens, updated_at)
+			SELECT $1, CASE WHEN tokens >= 1 THEN tokens - 1 ELSE tokens END, $4 FROM cur
+			ON CONFLICT (key) DO UPDATE
+				SET tokens = EXCLUDED.tokens, updated_at = GREATEST(rate_limits.updated_at, EXCLUDED.updated_at)
+		)
+		SELECT tokens FROM cur`
+	qDeleteIdleRateLimits = "DELETE FROM rate_limits WHERE updated_at < $1"
+)
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/crewblade/notes_service/internal/domain/errs"
+	"github.com/crewblade/notes_service/internal/lib/logging"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgAdminShutdown        = "57P01"
+	pgCrashShutdown        = "57P02"
+	pgCannotConnectNow     = "57P03"
+)
+
+// retrier reruns operations that failed with a transient error, with
+// exponential backoff and full jitter, as long as the context deadline allows.
+type retrier struct {
+	log         *slog.Logger
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	observer    QueryObserver
+
+	counts sync.Map // op -> *atomic.Int64
+}
+
+func newRetrier(log *slog.Logger, opts Options) *retrier {
+	r := &retrier{
+		log:         log,
+		maxAttempts: opts.RetryMaxAttempts,
+		baseDelay:   opts.RetryBaseDelay,
+		maxDelay:    opts.RetryMaxDelay,
+		observer:    opts.Observer,
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = 1
+	}
+	if r.baseDelay <= 0 {
+		r.baseDelay = 50 * time.Millisecond
+	}
+	if r.maxDelay < r.baseDelay {
+		r.maxDelay = r.baseDelay
+	}
+	return r
+}
+
+// do runs fn until it succeeds, fails permanently or runs out of attempts.
+// A transient error that outlives the retries is returned as errs.Transient.
+// idempotent tells whether fn may be rerun after a failure that left its
+// outcome unknown, such as a connection reset mid-query.
+func (r *retrier) do(ctx context.Context, op string, idempotent bool, fn func(ctx context.Context) error) (err error) {
+	ctx, span := tracer.Start(ctx, op, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	if r.observer != nil {
+		start := time.Now()
+		defer func() {
+			r.observer.ObserveQuery(op, time.Since(start), err)
+		}()
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !retryable(err, idempotent) {
+			return err
+		}
+		delay := r.backoff(attempt)
+		if attempt >= r.maxAttempts {
+			return errs.Transient(err, delay)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return errs.Transient(err, delay)
+		}
+		r.count(op)
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt), attribute.String("error", err.Error())))
+		logging.FromContext(ctx, r.log).Warn("retrying transient database error",
+			slog.String("op", op),
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("err", err.Error()),
+		)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *retrier) backoff(attempt int) time.Duration {
+	d := r.baseDelay << (attempt - 1)
+	if d <= 0 || d > r.maxDelay {
+		d = r.maxDelay
+	}
+	return time.Duration(rand.Int64N(int64(d)) + 1)
+}
+
+func (r *retrier) count(op string) {
+	c, _ := r.counts.LoadOrStore(op, new(atomic.Int64))
+	c.(*atomic.Int64).Add(1)
+}
+
+// Counts returns the number of retries per operation since start.
+func (r *retrier) Counts() map[string]int64 {
+	counts := make(map[string]int64)
+	r.counts.Range(func(op, c any) bool {
+		counts[op.(string)] = c.(*atomic.Int64).Load()
+		return true
+	})
+	return counts
+}
+
+// retryable reports whether err is transient. Serialization failures,
+// deadlocks and errors raised before the query reached the server never
+// leave partial effects, so they are retried for any operation.
+func retryable(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgSerializationFailure, pgDeadlockDetected, pgCannotConnectNow:
+			return true
+		case pgAdminShutdown, pgCrashShutdown:
+			return idempotent
+		}
+		// Class 08: connection exception.
+		return idempotent && len(pgErr.Code) == 5 && pgErr.Code[:2] == "08"
+	}
+	return idempotent && connectionLost(err)
+}
+
+func connectionLost(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr)
+}
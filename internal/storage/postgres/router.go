@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"github.com/crewblade/notes_service/internal/lib/caller"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaLagQuery returns how far a standby is behind, or zero on a primary.
+// The age of the last replayed transaction keeps growing while the primary
+// is idle, so a standby that has replayed all the WAL it received counts as
+// caught up.
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() THEN 0
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END`
+
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// router sends reads to healthy replicas in round-robin order and everything
+// else to the primary. A caller that wrote within the read-your-writes window
+// keeps reading from the primary.
+type router struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	next     atomic.Uint64
+	clock    Clock
+
+	maxLag        time.Duration
+	window        time.Duration
+	checkInterval time.Duration
+
+	mu         sync.Mutex
+	lastWrites map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newRouter(primary *pgxpool.Pool, replicas []*pgxpool.Pool, opts Options, clock Clock) *router {
+	r := &router{
+		primary:       primary,
+		clock:         clock,
+		maxLag:        opts.ReplicaMaxLag,
+		window:        opts.ReadYourWritesWindow,
+		checkInterval: opts.ReplicaCheckInterval,
+		lastWrites:    make(map[string]time.Time),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, pool := range replicas {
+		r.replicas = append(r.replicas, &replica{pool: pool})
+	}
+	if r.checkInterval <= 0 {
+		r.checkInterval = 5 * time.Second
+	}
+	go r.run()
+	return r
+}
+
+// reader picks the pool for a read-only query.
+func (r *router) reader(ctx context.Context) *pgxpool.Pool {
+	if len(r.replicas) == 0 || r.wroteRecently(ctx) {
+		return r.primary
+	}
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		rep := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if rep.healthy.Load() {
+			return rep.pool
+		}
+	}
+	return r.primary
+}
+
+// wrote records a write by the caller in ctx, if any.
+func (r *router) wrote(ctx context.Context) {
+	if len(r.replicas) == 0 || r.window <= 0 {
+		return
+	}
+	id, ok := caller.FromContext(ctx)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	r.lastWrites[id] = r.clock.Now()
+	r.mu.Unlock()
+}
+
+func (r *router) wroteRecently(ctx context.Context) bool {
+	id, ok := caller.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last, ok := r.lastWrites[id]
+	return ok && r.clock.Now().Sub(last) < r.window
+}
+
+func (r *router) run() {
+	defer close(r.done)
+	if len(r.replicas) == 0 {
+		<-r.stop
+		return
+	}
+	ticker := time.NewTicker(r.checkInterval)
+	defer ticker.Stop()
+	for {
+		r.checkReplicas()
+		r.forgetOldWrites()
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReplicas marks a replica healthy when it answers within the check
+// interval and lags no more than maxLag behind the primary.
+func (r *router) checkReplicas() {
+	var wg sync.WaitGroup
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), r.checkInterval)
+			defer cancel()
+			var lagSeconds float64
+			err := rep.pool.QueryRow(ctx, replicaLagQuery).Scan(&lagSeconds)
+			lag := time.Duration(lagSeconds * float64(time.Second))
+			rep.healthy.Store(err == nil && (r.maxLag <= 0 || lag <= r.maxLag))
+		}(rep)
+	}
+	wg.Wait()
+}
+
+func (r *router) forgetOldWrites() {
+	now := r.clock.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, last := range r.lastWrites {
+		if now.Sub(last) >= r.window {
+			delete(r.lastWrites, id)
+		}
+	}
+}
+
+func (r *router) close() {
+	close(r.stop)
+	<-r.done
+	for _, rep := range r.replicas {
+		rep.pool.Close()
+	}
+}
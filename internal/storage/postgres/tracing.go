@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/crewblade/notes_service/internal/storage/postgres")
+
+// queryTracer adds a span per statement under the span of the storage
+// operation. Only the SQL text is recorded: parameters hold note content.
+// Statements run outside of a trace, such as replica lag checks, are skipped.
+type queryTracer struct{}
+
+var baseAttributes = []attribute.KeyValue{attribute.String("db.system", "postgresql")}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	ctx, _ = tracer.Start(ctx, "postgres.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(baseAttributes...),
+		trace.WithAttributes(attribute.String("db.statement", data.SQL)),
+	)
+	return ctx
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	endSpan(ctx, data.CommandTag.RowsAffected(), data.Err)
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	ctx, _ = tracer.Start(ctx, "postgres.batch",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(baseAttributes...),
+		trace.WithAttributes(attribute.Int("db.batch.size", data.Batch.Len())),
+	)
+	return ctx
+}
+
+func (queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent("query", trace.WithAttributes(attribute.String("db.statement", data.SQL)))
+	if data.Err != nil {
+		span.RecordError(data.Err)
+	}
+}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	endSpan(ctx, -1, data.Err)
+}
+
+func endSpan(ctx context.Context, rows int64, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	if rows >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
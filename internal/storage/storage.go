@@ -1,7 +1,9 @@
 package storage
 
-import "errors"
+import "github.com/crewblade/notes_service/internal/domain/errs"
 
 var (
-	IdNotFound = errors.New("id not found")
+	IdNotFound      = errs.New(errs.NotFound, "ID_NOT_FOUND", "id not found")
+	IdAlreadyExists = errs.New(errs.Conflict, "ID_ALREADY_EXISTS", "id already exists")
+	BlobNotFound    = errs.New(errs.NotFound, "BLOB_NOT_FOUND", "blob not found")
 )
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS notes_content_hash_idx;
+ALTER TABLE notes DROP COLUMN IF EXISTS content_hash;
@@ -0,0 +1,3 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS content_hash TEXT;
+UPDATE notes SET content_hash = encode(sha256(convert_to(coalesce(content, ''), 'UTF8')), 'hex');
+CREATE INDEX IF NOT EXISTS notes_content_hash_idx ON notes (content_hash);
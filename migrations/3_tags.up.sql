@@ -0,0 +1,2 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
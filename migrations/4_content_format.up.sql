@@ -0,0 +1,2 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS content_format TEXT NOT NULL DEFAULT 'plain'
+    CHECK (content_format IN ('plain', 'markdown'));
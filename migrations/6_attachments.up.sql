@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS attachments (
+                                     id UUID PRIMARY KEY,
+                                     note_id UUID NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+                                     file_name TEXT NOT NULL,
+                                     mime_type TEXT NOT NULL,
+                                     size BIGINT NOT NULL,
+                                     sha256 TEXT NOT NULL,
+                                     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS attachments_note_id_idx ON attachments (note_id);
+CREATE INDEX IF NOT EXISTS attachments_sha256_idx ON attachments (sha256);
@@ -0,0 +1,12 @@
+-- Keys are chosen by clients, so they are only unique per caller.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+                                     caller_id TEXT NOT NULL,
+                                     key TEXT NOT NULL,
+                                     method TEXT NOT NULL,
+                                     request_hash TEXT NOT NULL,
+                                     response BYTEA,
+                                     created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                     expires_at TIMESTAMPTZ NOT NULL,
+                                     PRIMARY KEY (caller_id, key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+                                     key TEXT PRIMARY KEY,
+                                     tokens DOUBLE PRECISION NOT NULL,
+                                     updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limits_updated_at_idx ON rate_limits (updated_at);
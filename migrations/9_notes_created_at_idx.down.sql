@@ -0,0 +1 @@
+DROP INDEX IF EXISTS notes_created_at_id_idx;
@@ -0,0 +1,3 @@
+-- Pages are walked in (created_at, id) order; id breaks ties between notes
+-- created within the same instant.
+CREATE INDEX IF NOT EXISTS notes_created_at_id_idx ON notes (created_at, id);
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so binaries don't need the
+// directory at run time.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
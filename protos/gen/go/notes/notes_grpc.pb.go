@@ -27,6 +27,13 @@ type NotesClient interface {
 	GetNotes(ctx context.Context, in *GetNotesRequest, opts ...grpc.CallOption) (*GetNotesResponse, error)
 	UpdateNote(ctx context.Context, in *UpdateNoteRequest, opts ...grpc.CallOption) (*Note, error)
 	DeleteNote(ctx context.Context, in *DeleteNoteRequest, opts ...grpc.CallOption) (*Note, error)
+	ImportNotes(ctx context.Context, opts ...grpc.CallOption) (Notes_ImportNotesClient, error)
+	RenderNote(ctx context.Context, in *RenderNoteRequest, opts ...grpc.CallOption) (*RenderNoteResponse, error)
+	CreateNoteFromHtml(ctx context.Context, in *CreateNoteFromHtmlRequest, opts ...grpc.CallOption) (*Note, error)
+	UploadAttachment(ctx context.Context, opts ...grpc.CallOption) (Notes_UploadAttachmentClient, error)
+	DownloadAttachment(ctx context.Context, in *DownloadAttachmentRequest, opts ...grpc.CallOption) (Notes_DownloadAttachmentClient, error)
+	ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsResponse, error)
+	DeleteAttachment(ctx context.Context, in *DeleteAttachmentRequest, opts ...grpc.CallOption) (*Attachment, error)
 }
 
 type notesClient struct {
@@ -82,6 +89,142 @@ func (c *notesClient) DeleteNote(ctx context.Context, in *DeleteNoteRequest, opt
 	return out, nil
 }
 
+func (c *notesClient) ImportNotes(ctx context.Context, opts ...grpc.CallOption) (Notes_ImportNotesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Notes_ServiceDesc.Streams[0], "/notes.Notes/ImportNotes", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &notesImportNotesClient{stream}
+	return x, nil
+}
+
+type Notes_ImportNotesClient interface {
+	Send(*ImportNotesRequest) error
+	CloseAndRecv() (*ImportNotesResponse, error)
+	grpc.ClientStream
+}
+
+type notesImportNotesClient struct {
+	grpc.ClientStream
+}
+
+func (x *notesImportNotesClient) Send(m *ImportNotesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *notesImportNotesClient) CloseAndRecv() (*ImportNotesResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportNotesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *notesClient) RenderNote(ctx context.Context, in *RenderNoteRequest, opts ...grpc.CallOption) (*RenderNoteResponse, error) {
+	out := new(RenderNoteResponse)
+	err := c.cc.Invoke(ctx, "/notes.Notes/RenderNote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notesClient) CreateNoteFromHtml(ctx context.Context, in *CreateNoteFromHtmlRequest, opts ...grpc.CallOption) (*Note, error) {
+	out := new(Note)
+	err := c.cc.Invoke(ctx, "/notes.Notes/CreateNoteFromHtml", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notesClient) UploadAttachment(ctx context.Context, opts ...grpc.CallOption) (Notes_UploadAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Notes_ServiceDesc.Streams[1], "/notes.Notes/UploadAttachment", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &notesUploadAttachmentClient{stream}
+	return x, nil
+}
+
+type Notes_UploadAttachmentClient interface {
+	Send(*UploadAttachmentRequest) error
+	CloseAndRecv() (*Attachment, error)
+	grpc.ClientStream
+}
+
+type notesUploadAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *notesUploadAttachmentClient) Send(m *UploadAttachmentRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *notesUploadAttachmentClient) CloseAndRecv() (*Attachment, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Attachment)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *notesClient) DownloadAttachment(ctx context.Context, in *DownloadAttachmentRequest, opts ...grpc.CallOption) (Notes_DownloadAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Notes_ServiceDesc.Streams[2], "/notes.Notes/DownloadAttachment", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &notesDownloadAttachmentClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Notes_DownloadAttachmentClient interface {
+	Recv() (*DownloadAttachmentResponse, error)
+	grpc.ClientStream
+}
+
+type notesDownloadAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *notesDownloadAttachmentClient) Recv() (*DownloadAttachmentResponse, error) {
+	m := new(DownloadAttachmentResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *notesClient) ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsResponse, error) {
+	out := new(ListAttachmentsResponse)
+	err := c.cc.Invoke(ctx, "/notes.Notes/ListAttachments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notesClient) DeleteAttachment(ctx context.Context, in *DeleteAttachmentRequest, opts ...grpc.CallOption) (*Attachment, error) {
+	out := new(Attachment)
+	err := c.cc.Invoke(ctx, "/notes.Notes/DeleteAttachment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NotesServer is the server API for Notes service.
 // All implementations must embed UnimplementedNotesServer
 // for forward compatibility
@@ -91,6 +234,13 @@ type NotesServer interface {
 	GetNotes(context.Context, *GetNotesRequest) (*GetNotesResponse, error)
 	UpdateNote(context.Context, *UpdateNoteRequest) (*Note, error)
 	DeleteNote(context.Context, *DeleteNoteRequest) (*Note, error)
+	ImportNotes(Notes_ImportNotesServer) error
+	RenderNote(context.Context, *RenderNoteRequest) (*RenderNoteResponse, error)
+	CreateNoteFromHtml(context.Context, *CreateNoteFromHtmlRequest) (*Note, error)
+	UploadAttachment(Notes_UploadAttachmentServer) error
+	DownloadAttachment(*DownloadAttachmentRequest, Notes_DownloadAttachmentServer) error
+	ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsResponse, error)
+	DeleteAttachment(context.Context, *DeleteAttachmentRequest) (*Attachment, error)
 	mustEmbedUnimplementedNotesServer()
 }
 
@@ -113,6 +263,27 @@ func (UnimplementedNotesServer) UpdateNote(context.Context, *UpdateNoteRequest)
 func (UnimplementedNotesServer) DeleteNote(context.Context, *DeleteNoteRequest) (*Note, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteNote not implemented")
 }
+func (UnimplementedNotesServer) ImportNotes(Notes_ImportNotesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportNotes not implemented")
+}
+func (UnimplementedNotesServer) RenderNote(context.Context, *RenderNoteRequest) (*RenderNoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenderNote not implemented")
+}
+func (UnimplementedNotesServer) CreateNoteFromHtml(context.Context, *CreateNoteFromHtmlRequest) (*Note, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateNoteFromHtml not implemented")
+}
+func (UnimplementedNotesServer) UploadAttachment(Notes_UploadAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadAttachment not implemented")
+}
+func (UnimplementedNotesServer) DownloadAttachment(*DownloadAttachmentRequest, Notes_DownloadAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadAttachment not implemented")
+}
+func (UnimplementedNotesServer) ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAttachments not implemented")
+}
+func (UnimplementedNotesServer) DeleteAttachment(context.Context, *DeleteAttachmentRequest) (*Attachment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAttachment not implemented")
+}
 func (UnimplementedNotesServer) mustEmbedUnimplementedNotesServer() {}
 
 // UnsafeNotesServer may be embedded to opt out of forward compatibility for this service.
@@ -216,6 +387,151 @@ func _Notes_DeleteNote_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Notes_ImportNotes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NotesServer).ImportNotes(&notesImportNotesServer{stream})
+}
+
+type Notes_ImportNotesServer interface {
+	SendAndClose(*ImportNotesResponse) error
+	Recv() (*ImportNotesRequest, error)
+	grpc.ServerStream
+}
+
+type notesImportNotesServer struct {
+	grpc.ServerStream
+}
+
+func (x *notesImportNotesServer) SendAndClose(m *ImportNotesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *notesImportNotesServer) Recv() (*ImportNotesRequest, error) {
+	m := new(ImportNotesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Notes_RenderNote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderNoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotesServer).RenderNote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notes.Notes/RenderNote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotesServer).RenderNote(ctx, req.(*RenderNoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Notes_CreateNoteFromHtml_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateNoteFromHtmlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotesServer).CreateNoteFromHtml(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notes.Notes/CreateNoteFromHtml",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotesServer).CreateNoteFromHtml(ctx, req.(*CreateNoteFromHtmlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Notes_UploadAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NotesServer).UploadAttachment(&notesUploadAttachmentServer{stream})
+}
+
+type Notes_UploadAttachmentServer interface {
+	SendAndClose(*Attachment) error
+	Recv() (*UploadAttachmentRequest, error)
+	grpc.ServerStream
+}
+
+type notesUploadAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *notesUploadAttachmentServer) SendAndClose(m *Attachment) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *notesUploadAttachmentServer) Recv() (*UploadAttachmentRequest, error) {
+	m := new(UploadAttachmentRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Notes_DownloadAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadAttachmentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NotesServer).DownloadAttachment(m, &notesDownloadAttachmentServer{stream})
+}
+
+type Notes_DownloadAttachmentServer interface {
+	Send(*DownloadAttachmentResponse) error
+	grpc.ServerStream
+}
+
+type notesDownloadAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *notesDownloadAttachmentServer) Send(m *DownloadAttachmentResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Notes_ListAttachments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAttachmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotesServer).ListAttachments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notes.Notes/ListAttachments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotesServer).ListAttachments(ctx, req.(*ListAttachmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Notes_DeleteAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotesServer).DeleteAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/notes.Notes/DeleteAttachment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotesServer).DeleteAttachment(ctx, req.(*DeleteAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Notes_ServiceDesc is the grpc.ServiceDesc for Notes service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -243,7 +559,39 @@ var Notes_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteNote",
 			Handler:    _Notes_DeleteNote_Handler,
 		},
+		{
+			MethodName: "RenderNote",
+			Handler:    _Notes_RenderNote_Handler,
+		},
+		{
+			MethodName: "CreateNoteFromHtml",
+			Handler:    _Notes_CreateNoteFromHtml_Handler,
+		},
+		{
+			MethodName: "ListAttachments",
+			Handler:    _Notes_ListAttachments_Handler,
+		},
+		{
+			MethodName: "DeleteAttachment",
+			Handler:    _Notes_DeleteAttachment_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ImportNotes",
+			Handler:       _Notes_ImportNotes_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "UploadAttachment",
+			Handler:       _Notes_UploadAttachment_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadAttachment",
+			Handler:       _Notes_DownloadAttachment_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "notes/notes.proto",
 }